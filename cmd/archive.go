@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/archive"
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/persona"
+)
+
+// turnInput 语音提问的录音和纠正前的识别结果，在下一次提问时存档
+type turnInput struct {
+	wav        []byte
+	transcript string
+	corrected  string // 纠正模型改过的识别结果，没有改时为空
+}
+
+// teeAudio 把合成的音频转发给播放器，同时复制一份到buf用于存档。
+// 播放器被停止后可能先返回，copied关闭后buf才完整
+func teeAudio(in <-chan []byte, buf *bytes.Buffer) (out chan []byte, copied <-chan struct{}) {
+	out = make(chan []byte, cap(in))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer close(out)
+		for data := range in {
+			buf.Write(data)
+			out <- data
+		}
+	}()
+	return out, done
+}
+
+// saveTurn 把一轮对话的提问、回答、设置和codec编码的音频存档
+func saveTurn(start time.Time, question, answer string, input *turnInput, audio []byte, codec string, cast *persona.Persona) {
+	turn := archive.Turn{
+		Time:      start,
+		Question:  question,
+		Answer:    answer,
+		Model:     modelName,
+		ASREngine: recognizeOptions().Engine,
+	}
+	if tencentEnabled {
+		turn.Voice, turn.Emotion = voiceType, emotionCategory
+	}
+	if cast != nil {
+		turn.Persona = cast.Name
+	}
+	var wav []byte
+	if input != nil {
+		wav, turn.Transcript = input.wav, input.transcript
+	}
+
+	dir, err := turnArchive.Save(turn, wav, audio, codec)
+	if err != nil {
+		log.Warnf("保存对话存档失败: %v", err)
+		return
+	}
+	log.Debugf("对话已存档: %s", dir)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	log "github.com/sirupsen/logrus"
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/audio"
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/recorder"
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/vad"
+)
+
+// listener 免提模式：持续录音，用VAD检测到一句话说完后，把这句话的录音（WAV）交给主循环
+type listener struct {
+	recorder   *recorder.Recorder
+	cfg        vad.Config
+	utterances chan []byte
+
+	listening bool
+	stop      chan struct{} // Stop时关闭，不再等待主循环接收
+}
+
+func newListener(r *recorder.Recorder, cfg vad.Config) *listener {
+	return &listener{recorder: r, cfg: cfg, utterances: make(chan []byte)}
+}
+
+// Start 开始聆听，检测到第一句话后交给utterances，之后的录音丢弃，直到Stop
+func (l *listener) Start() error {
+	wav, err := l.recorder.StartStream()
+	if err != nil {
+		return err
+	}
+	l.listening = true
+	stop := make(chan struct{})
+	l.stop = stop
+
+	utterances := vad.Segment(audio.PCMStream(wav, audio.Mono16k), l.cfg)
+	go func() {
+		sent := false
+		for pcm := range utterances {
+			if sent {
+				continue
+			}
+			log.Debugf("检测到一句话，时长%v", audio.Mono16k.Duration(len(pcm)))
+			select {
+			case l.utterances <- append(audio.WAVHeader(audio.Mono16k, len(pcm)), pcm...):
+			case <-stop:
+			}
+			sent = true
+		}
+	}()
+	return nil
+}
+
+// Stop 停止聆听
+func (l *listener) Stop() {
+	if !l.listening {
+		return
+	}
+	l.listening = false
+	close(l.stop)
+	l.recorder.Stop()
+}
+
+// rateLimit 在window时间内最多允许max次，max为0时不限制
+type rateLimit struct {
+	max    int
+	window time.Duration
+	times  []time.Time // window内已经允许的时间
+}
+
+// Allow 现在是否还允许一次，允许时计入次数
+func (l *rateLimit) Allow(now time.Time) bool {
+	if l.max <= 0 {
+		return true
+	}
+	for len(l.times) > 0 && now.Sub(l.times[0]) >= l.window {
+		l.times = l.times[1:]
+	}
+	if len(l.times) >= l.max {
+		return false
+	}
+	l.times = append(l.times, now)
+	return true
+}
+
+// isStopCommand 识别结果是否为结束对话的命令，忽略标点和空白
+func isStopCommand(text string) bool {
+	text = stripPunct(text)
+	for _, phrase := range stopPhrases {
+		if text == stripPunct(phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWakePhrase 识别结果中是否有唤醒词，返回唤醒词后面的内容（去掉开头的标点）。
+// 标点、空白和大小写不影响匹配，如“hey，siri”能匹配“Hey Siri”
+func matchWakePhrase(text string) (string, bool) {
+	// 去掉标点后的文字，以及每个字在原文中结束的位置
+	var letters []rune
+	var ends []int
+	for i, r := range text {
+		if unicode.IsPunct(r) || unicode.IsSpace(r) {
+			continue
+		}
+		letters = append(letters, unicode.ToLower(r))
+		ends = append(ends, i+utf8.RuneLen(r))
+	}
+
+	for _, phrase := range wakePhrases {
+		p := []rune(strings.ToLower(stripPunct(phrase)))
+		if len(p) == 0 {
+			continue
+		}
+		if i := strings.Index(string(letters), string(p)); i >= 0 {
+			end := ends[utf8.RuneCountInString(string(letters)[:i])+len(p)-1]
+			return strings.TrimLeftFunc(text[end:], func(r rune) bool { return unicode.IsPunct(r) || unicode.IsSpace(r) }), true
+		}
+	}
+	return "", false
+}
+
+// stripPunct 去掉标点和空白
+func stripPunct(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsPunct(r) || unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// splitList 解析逗号分隔的列表
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
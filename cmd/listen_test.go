@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStripPunct(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"停止对话。", "停止对话"},
+		{" 你好，小智！", "你好小智"},
+		{"Hey, Siri?", "HeySiri"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := stripPunct(tt.in); got != tt.want {
+			t.Errorf("stripPunct(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIsStopCommand(t *testing.T) {
+	defer func(old []string) { stopPhrases = old }(stopPhrases)
+	stopPhrases = []string{"停止对话", "结束对话"}
+
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"停止对话", true},
+		{"停止对话。", true},
+		{"结束，对话！", true},
+		{"停止对话吧", false}, // 多了字的不算
+		{"停止", false},
+		{"请帮我停止对话", false},
+	}
+	for _, tt := range tests {
+		if got := isStopCommand(tt.text); got != tt.want {
+			t.Errorf("isStopCommand(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMatchWakePhrase(t *testing.T) {
+	defer func(old []string) { wakePhrases = old }(wakePhrases)
+	wakePhrases = []string{"你好小智", "Hey Siri"}
+
+	tests := []struct {
+		text string
+		rest string
+		ok   bool
+	}{
+		{"你好小智", "", true},
+		{"你好，小智。", "", true},
+		{"你好小智，今天天气怎么样？", "今天天气怎么样？", true},
+		{"嗯，你好小智 现在几点", "现在几点", true}, // 唤醒词前面的话忽略
+		{"hey，siri! what time is it", "what time is it", true},
+		{"你好小志", "", false}, // 同音字不算
+		{"你好小", "", false},
+		{"小智你好", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		rest, ok := matchWakePhrase(tt.text)
+		if rest != tt.rest || ok != tt.ok {
+			t.Errorf("matchWakePhrase(%q) = %q, %v, want %q, %v", tt.text, rest, ok, tt.rest, tt.ok)
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	start := time.Now()
+	l := &rateLimit{max: 2, window: time.Minute}
+	if !l.Allow(start) || !l.Allow(start.Add(time.Second)) {
+		t.Fatal("first two calls should be allowed")
+	}
+	if l.Allow(start.Add(30 * time.Second)) {
+		t.Error("third call within a minute should be refused")
+	}
+	if !l.Allow(start.Add(time.Minute)) {
+		t.Error("call after the first one expired should be allowed")
+	}
+
+	unlimited := &rateLimit{window: time.Minute}
+	for i := 0; i < 100; i++ {
+		if !unlimited.Allow(start) {
+			t.Fatal("max 0 should not limit")
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"strconv"
@@ -13,17 +14,24 @@ import (
 
 	"os"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/sashabaranov/go-openai"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/archive"
 	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/asr"
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/audio"
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/persona"
 	myplayer "gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/player"
 	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/recorder"
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/replay"
 	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/tts"
 	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/tui"
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/vad"
 )
 
 var (
@@ -47,10 +55,162 @@ var (
 	emotionCategory = "neutral"
 	speed           = float64(1)
 
+	// 发音词典，以及合成后端是否支持SSML
+	lexiconFile = envOr("TTS_LEXICON", "lexicon.txt")
+	ssml, _     = strconv.ParseBool(os.Getenv("TTS_SSML"))
+	lexicon     *tts.Lexicon
+
+	// 合成方式：sentence 每句话一个连接；stream 一轮对话一个流式会话，文字边到边发
+	ttsMode = envOr("TTS_MODE", "sentence")
+	ttsPool = tts.NewSessionPool(1, 30*time.Second) // stream模式下预先建立的会话
+	// 合成语音的编码：mp3、pcm、wav或opus（Ogg封装），播放时选用对应的解码器
+	ttsCodec = envOr("TTS_CODEC", "mp3")
+
+	// 角色扮演设定，currentPersona为空时用选中的音色朗读全部内容
+	personaFile    = envOr("PERSONA_FILE", "personas.json")
+	personas       []*persona.Persona
+	currentPersona *persona.Persona
+
 	processing = false
+
+	// 对话存档目录，每轮对话一个子目录，保存录音、识别结果、设置、回答和朗读的音频。为空时不存档
+	archiveDir  = os.Getenv("ARCHIVE_DIR")
+	turnArchive *archive.Archive
+	voiceInput  *turnInput // 等待发送给AI的语音提问，打字提问时为nil
+
+	// 重播用的语音在内存中最多保存多少MB（默认64），超出的写到REPLAY_SPILL_DIR下，没有设置时丢掉最早的
+	replayMemoryMB = envFloat("REPLAY_MEMORY_MB")
+	replaySpillDir = os.Getenv("REPLAY_SPILL_DIR")
+
+	tencentEnabled bool // 是否配置了腾讯云，没有配置时不朗读回答
+
+	// 语音输出到哪里，由-sink选择，没有声卡的服务器上也可以跑完整个流程
+	audioSink myplayer.Sink
+
+	// 识别方式：sentence 录音结束后整段识别；stream 边录音边识别，输入框中实时显示识别结果
+	asrMode = envOr("ASR_MODE", "sentence")
+	// 识别后端：tencent 腾讯云一句话识别；openai OpenAI兼容的 /v1/audio/transcriptions。默认有腾讯云配置时用腾讯云
+	asrBackend = os.Getenv("ASR_BACKEND")
+	asrModel   = os.Getenv("ASR_MODEL") // openai后端使用的模型，默认whisper-1
+	// 录音后端：sox、pw-record、parecord、arecord、ffmpeg，或者"file:路径"从文件读取。为空时自动选择
+	recorderBackend = os.Getenv("RECORDER_BACKEND")
+
+	// 语音输入方式：manual 点击输入框或按快捷键开始、结束录音；continuous 连续对话，回答后自动开始下一次录音；
+	// handsfree 免提，自动检测说话的开始和结束；wake 免提，但要先说唤醒词
+	voiceMode = envOr("VOICE_MODE", "manual")
+	// 唤醒模式（VOICE_MODE=wake）的唤醒词，逗号分隔；唤醒后等待提问的时间
+	wakePhrases         = splitList(envOr("WAKE_PHRASES", "你好小智"))
+	wakeTimeoutDuration = envDurationOr("WAKE_TIMEOUT", 8*time.Second)
+	// 待机时每句话都要调用一次识别服务才知道有没有唤醒词，每分钟最多识别几句，为0时不限制
+	wakeMaxPerMinute, _ = strconv.Atoi(envOr("WAKE_MAX_PER_MINUTE", "10"))
+	// 按键说话的快捷键，以及是否按住说话（toggle 按一下开始、再按一下结束；hold 按住说话）
+	pttKey  = envOr("PTT_KEY", "ctrl+r")
+	pttMode = envOr("PTT_MODE", "toggle")
+	// 连续对话和免提模式下，说出这些话时结束，不发送给AI
+	stopPhrases = splitList(envOr("VOICE_STOP_PHRASES", "停止对话,结束对话,退出对话"))
+	// 免提模式的参数：一句话结束前的静音时长、检测到说话前保留的录音、说话的最小音量（为0时自动）
+	vadConfig = vad.Config{
+		EndSilence: envDuration("VAD_END_SILENCE"),
+		PreRoll:    envDuration("VAD_PREROLL"),
+		Threshold:  envFloat("VAD_THRESHOLD"),
+	}
+
+	// 纠正识别结果用的模型，为空时不纠正
+	asrCorrectModel = os.Getenv("ASR_CORRECT_MODEL")
+	corrector       *asr.Corrector
+	// 确认模式：识别结果先填入输入框，修改后按回车再发送
+	asrConfirm = envBool("ASR_CONFIRM")
+	// 识别前预处理录音：转为16k单声道、去掉首尾静音、压低噪声、归一化音量
+	asrPreprocess = envOr("ASR_PREPROCESS", "true") == "true"
+
+	// 识别引擎：auto 跟随所选音色的语言；也可以指定16k_zh、16k_en等，见asr.Engines
+	asrEngine = envOr("ASR_ENGINE", "auto")
+	// 识别参数：热词（逗号分隔，可写成“词|权重”）、数字转换方式、过滤选项
+	asrOptions = asr.Options{
+		Hotwords:    asr.ParseHotwords(os.Getenv("ASR_HOTWORDS")),
+		ConvertNum:  os.Getenv("ASR_CONVERT_NUM"),
+		FilterDirty: envBool("ASR_FILTER_DIRTY"),
+		FilterModal: envBool("ASR_FILTER_MODAL"),
+		FilterPunc:  envBool("ASR_FILTER_PUNC"),
+	}
 )
 
+// recognizeOptions 本次识别的参数，auto时根据当前音色的语言选择引擎；唤醒模式下唤醒词作为超级热词
+func recognizeOptions() asr.Options {
+	opts := asrOptions
+	if voiceMode == "wake" {
+		opts.Hotwords = append([]string(nil), asrOptions.Hotwords...)
+		for _, phrase := range wakePhrases {
+			opts.Hotwords = append(opts.Hotwords, phrase+"|11")
+		}
+	}
+	opts.Engine = asrEngine
+	if asrEngine == "auto" {
+		opts.Engine = asr.DefaultEngine
+		if v, ok := tts.LookupVoice(voiceType); ok {
+			opts.Engine = asr.EngineForLanguage(v.Language)
+		}
+	}
+	return opts
+}
+
+// newSpeaker 打开扬声器，编译时包含了扬声器（见speaker.go）才有
+var newSpeaker func() (myplayer.Sink, error)
+
+// newSink 根据-sink创建语音输出。打不开扬声器时不退出，改为只按时长播放
+func newSink(name, dir string) (myplayer.Sink, error) {
+	switch name {
+	case "speaker":
+		if newSpeaker == nil {
+			log.Warn("编译时没有包含扬声器（CGO_ENABLED=0或-tags nospeaker），语音不会播放出来")
+			return myplayer.NullSink{}, nil
+		}
+		s, err := newSpeaker()
+		if err != nil {
+			log.Warnf("打开扬声器失败，语音不会播放出来: %v", err)
+			return myplayer.NullSink{}, nil
+		}
+		return s, nil
+	case "file":
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+		return myplayer.FileSink{Dir: dir}, nil
+	case "stdout":
+		return myplayer.WriterSink{W: os.Stdout}, nil
+	case "null":
+		return myplayer.NullSink{}, nil
+	}
+	return nil, fmt.Errorf("未知的语音输出: %s", name)
+}
+
+// newRecognizer 根据配置创建语音识别客户端
+func newRecognizer(client *openai.Client) (asr.Recognizer, error) {
+	if asrBackend == "" {
+		asrBackend = "openai"
+		if tencentEnabled {
+			asrBackend = "tencent"
+		}
+	}
+
+	switch asrBackend {
+	case "tencent":
+		if !tencentEnabled {
+			return nil, errors.New("腾讯云语音识别需要设置TENCENTCLOUD_APP_ID等环境变量")
+		}
+		return asr.NewClient(common.NewCredential(secretId, secretKey))
+	case "openai":
+		return asr.NewOpenAIClient(client, asrModel), nil
+	}
+	return nil, fmt.Errorf("未知的语音识别后端: %s", asrBackend)
+}
+
 func main() {
+	audioFile := flag.String("audio", "", "启动后把这个WAV或MP3文件识别后作为第一个问题")
+	sinkName := flag.String("sink", "speaker", "语音输出到哪里：speaker 扬声器；file 每轮的语音写入-sink-dir下的一个文件；stdout 16k双声道16位PCM输出到标准输出，界面改为输出到标准错误；null 不输出，只按时长播放")
+	sinkDir := flag.String("sink-dir", "speech", "-sink file时保存语音的目录")
+	flag.Parse()
+
 	f, err := tea.LogToFile("debug.log", "debug")
 	if err != nil {
 		fmt.Println("fatal:", err)
@@ -75,20 +235,26 @@ func main() {
 	log.SetOutput(f)
 	log.SetLevel(log.DebugLevel)
 
-	if appId == 0 {
-		log.Fatal("请设置TENCENTCLOUD_APP_ID环境变量")
-	}
-
 	if apiKey == "" {
 		log.Fatal("请设置OPENAI_API_KEY环境变量")
 	}
 
-	if secretId == "" {
-		log.Fatal("请设置TENCENTCLOUD_SECRET_ID环境变量")
-	}
+	// 腾讯云的配置可以都不设置，此时用OpenAI兼容接口识别语音，并且不朗读回答
+	tencentEnabled = appId != 0 || secretId != "" || secretKey != ""
+	if tencentEnabled {
+		if appId == 0 {
+			log.Fatal("请设置TENCENTCLOUD_APP_ID环境变量")
+		}
 
-	if secretKey == "" {
-		log.Fatal("请设置TENCENTCLOUD_SECRET_KEY环境变量")
+		if secretId == "" {
+			log.Fatal("请设置TENCENTCLOUD_SECRET_ID环境变量")
+		}
+
+		if secretKey == "" {
+			log.Fatal("请设置TENCENTCLOUD_SECRET_KEY环境变量")
+		}
+	} else {
+		log.Warn("未设置腾讯云的配置，将不会朗读回答")
 	}
 
 	// 使用你的OpenAI API密钥创建客户端
@@ -103,53 +269,355 @@ func main() {
 		log.Fatal("client is nil")
 	}
 
-	credential := common.NewCredential(
-		os.Getenv("TENCENTCLOUD_SECRET_ID"),
-		os.Getenv("TENCENTCLOUD_SECRET_KEY"),
-	)
+	recognizer, err := newRecognizer(client)
+	if err != nil {
+		log.Fatalf("创建语音识别客户端失败: %v", err)
+	}
+	if asrOptions.ConvertNum, err = asr.ParseConvertNum(asrOptions.ConvertNum); err != nil {
+		log.Fatal(err)
+	}
+	if _, ok := asr.LookupEngine(asrEngine); !ok && asrEngine != "auto" {
+		log.Fatalf("未知的识别引擎: %s", asrEngine)
+	}
+	if asrCorrectModel != "" {
+		corrector = asr.NewCorrector(client, asrCorrectModel)
+	}
+	if asrMode == "stream" && asrBackend != "tencent" {
+		log.Warnf("实时语音识别只支持腾讯云，%s将整段识别", asrBackend)
+		asrMode = "sentence"
+	}
+
+	lexicon, err = tts.LoadLexicon(lexiconFile)
+	if err != nil {
+		log.Warnf("加载发音词典失败: %v", err)
+	}
+
+	if archiveDir != "" {
+		if turnArchive, err = archive.New(archiveDir); err != nil {
+			log.Fatalf("创建对话存档目录失败: %v", err)
+		}
+	}
+	if replayMemoryMB <= 0 {
+		replayMemoryMB = 64
+	}
+	answerStore = replay.NewStore(int64(replayMemoryMB*(1<<20)), replaySpillDir)
+	defer answerStore.Close()
 
-	asrClient, err := asr.NewClient(credential)
+	personas, err = persona.Load(personaFile)
 	if err != nil {
-		panic(err)
+		log.Warnf("加载角色扮演设定失败: %v", err)
 	}
 
-	recorder := recorder.NewRecorder()
+	audioSink, err = newSink(*sinkName, *sinkDir)
+	if err != nil {
+		log.Fatalf("选择语音输出失败: %v", err)
+	}
+	if _, err := myplayer.DecoderFor(ttsCodec); err != nil {
+		log.Fatalf("TTS_CODEC只支持%s: %v", strings.Join(myplayer.Codecs, "、"), err)
+	}
+	if tencentEnabled && ttsMode == "stream" {
+		ttsPool.Warm(streamConfig(voiceType, emotionCategory))
+	}
+
+	// 自动选择时找不到录音程序也可以运行，只是不能语音输入
+	backend, err := recorder.Detect(recorderBackend)
+	if err != nil && recorderBackend != "" {
+		log.Fatalf("选择录音后端失败: %v", err)
+	} else if err != nil {
+		log.Warn(err)
+	} else {
+		log.Infof("录音后端: %s", backend.Name())
+	}
+	recorder := recorder.NewRecorder(backend)
+	realtimeASR := asr.NewRealtimeClient(asr.RealtimeConfig{
+		AppID:     appId,
+		SecretID:  secretId,
+		SecretKey: secretKey,
+	})
+
+	listen := newListener(recorder, vadConfig)
 
 	// 创建和UI交互的事件通道
 	eventChan := make(chan tui.Event, 1)
 	inChan := make(chan tui.Event, 1)
+
+	// 录音时把音量转给界面显示
+	go func() {
+		for l := range recorder.Levels() {
+			level, _ := json.Marshal(tui.Level{RMS: l.RMS, Peak: l.Peak, Elapsed: l.Elapsed, Silence: l.Silence})
+			inChan <- tui.Event{Type: "level", Payload: string(level)}
+		}
+	}()
+
+	// 播放控制马上执行，不经过主循环：朗读回答时主循环正在等这轮对话结束
+	controlChan := make(chan tui.Event, 4)
 	go func() {
-		for e := range eventChan {
+		for e := range controlChan {
+			notice := nowPlaying.control(e.Payload)
+			go func() { inChan <- tui.Event{Type: "notify", Payload: notice} }()
+		}
+	}()
+	go func() {
+		var streamResult chan recognition // stream识别模式下，本次录音的识别结果
+		var wakeTimeout <-chan time.Time  // 唤醒后等待提问的超时，待机时为nil
+		wakeLimit := &rateLimit{max: wakeMaxPerMinute, window: time.Minute}
+
+		// startRecording 开始一次录音，失败时告诉界面
+		startRecording := func() bool {
+			var err error
+			if asrMode == "stream" {
+				var wav <-chan []byte
+				if wav, err = recorder.StartStream(); err == nil {
+					streamResult = make(chan recognition, 1)
+					go streamASR(realtimeASR, wav, recognizeOptions(), inChan, streamResult)
+				}
+			} else {
+				err = recorder.Start()
+			}
+			if err != nil {
+				log.Errorf("开始录音失败: %v", err)
+				inChan <- tui.Event{Type: "record_error", Payload: fmt.Sprintf("开始录音失败: %v", err)}
+				return false
+			}
+			return true
+		}
+
+		// startListening 免提模式下开始聆听，并告诉界面
+		startListening := func() {
+			if err := listen.Start(); err != nil {
+				log.Errorf("开始聆听失败: %v", err)
+				voiceMode = "manual"
+				inChan <- tui.Event{Type: "record_error", Payload: fmt.Sprintf("开始聆听失败: %v", err)}
+				return
+			}
+			state := "on"
+			if voiceMode == "wake" && wakeTimeout == nil {
+				state = "wake"
+			}
+			inChan <- tui.Event{Type: "listening", Payload: state}
+		}
+		// stopVoiceMode 退出免提模式和连续对话，正在进行的录音直接丢弃
+		stopVoiceMode := func() {
+			listen.Stop()
+			if recorder.Recording() {
+				recorder.Stop()
+				if streamResult != nil {
+					<-streamResult
+					streamResult = nil
+				}
+			}
+			inChan <- tui.Event{Type: "listening", Payload: "off"}
+			inChan <- tui.Event{Type: "recording", Payload: "off"}
+			switch voiceMode {
+			case "handsfree":
+				inChan <- tui.Event{Type: "notify", Payload: "已退出免提模式"}
+			case "continuous":
+				inChan <- tui.Event{Type: "notify", Payload: "已结束连续对话"}
+			case "wake":
+				inChan <- tui.Event{Type: "notify", Payload: "已退出唤醒模式"}
+			}
+			voiceMode = "manual"
+			wakeTimeout = nil
+		}
+
+		// startContinuous 开始连续对话，先开始第一次录音
+		startContinuous := func() {
+			if startRecording() {
+				inChan <- tui.Event{Type: "recording", Payload: "on"}
+				inChan <- tui.Event{Type: "notify", Payload: "连续对话：说完后结束录音，回答后会自动继续录音，说“停止对话”或输入 /stop 结束"}
+			}
+		}
+
+		switch voiceMode {
+		case "handsfree", "wake":
+			startListening()
+		case "continuous":
+			startContinuous()
+		}
+
+		for {
+			var e tui.Event
+			select {
+			case wav := <-listen.utterances:
+				// 免提模式下说完了一句话，回答朗读完后再继续聆听
+				listen.Stop()
+				inChan <- tui.Event{Type: "listening", Payload: "off"}
+				if voiceMode == "wake" && wakeTimeout == nil && !wakeLimit.Allow(time.Now()) {
+					// 周围一直有人说话时不再逐句识别，过一会儿再恢复
+					log.Debugf("待机时一分钟内已经识别了%d句话，忽略这句", wakeMaxPerMinute)
+					startListening()
+					continue
+				}
+				question, err := recognizeRecording(recognizer, wav, nil, inChan)
+				if voiceMode == "wake" && wakeTimeout == nil {
+					// 待机时只关心唤醒词，其余的话都忽略
+					rest, ok := matchWakePhrase(question)
+					if err != nil || !ok {
+						log.Debugf("没有唤醒: %q, %v", question, err)
+						startListening()
+						continue
+					}
+					if rest == "" {
+						// 只说了唤醒词：提示一声，等待提问。提示音放完再聆听，免得录进去
+						wakeTimeout = time.After(wakeTimeoutDuration)
+						myplayer.PlayPCM(audioSink, myplayer.Chime())
+						inChan <- tui.Event{Type: "notify", Payload: "我在，请说"}
+						startListening()
+						continue
+					}
+					// 唤醒词后面直接跟着问题
+					question = rest
+				}
+				wakeTimeout = nil
+
+				if err == nil && isStopCommand(question) {
+					stopVoiceMode()
+					continue
+				}
+				askTranscript(client, wav, question, err, inChan)
+				if voiceMode == "handsfree" || voiceMode == "wake" {
+					startListening()
+				}
+				continue
+			case <-wakeTimeout:
+				// 唤醒后一直没有提问，回到待机
+				wakeTimeout = nil
+				inChan <- tui.Event{Type: "listening", Payload: "wake"}
+				inChan <- tui.Event{Type: "notify", Payload: "没有听到问题，回到待机"}
+				continue
+			case ev, ok := <-eventChan:
+				if !ok {
+					log.Fatal("main|事件通道已关闭")
+				}
+				e = ev
+			}
+
 			log.Debug("recv event from main loop", e)
 			switch e.Type {
 			case "model":
 				modelName = e.Payload
 			case "tone":
-				voiceType, _ = strconv.ParseInt(e.Payload, 10, 64)
+				tone, _ := strconv.ParseInt(e.Payload, 10, 64)
+				if v, ok := tts.LookupVoice(tone); ok && !v.SupportsSampleRate(tts.SampleRate) {
+					log.Warnf("音色%d不支持%d采样率: %v", tone, tts.SampleRate, v.SampleRates)
+					inChan <- tui.Event{Type: "notify", Payload: fmt.Sprintf("音色%d不支持%dHz合成，仍使用音色%d", tone, tts.SampleRate, voiceType)}
+					break
+				}
+				voiceType = tone
+				if ttsMode == "stream" {
+					ttsPool.Warm(streamConfig(voiceType, emotionCategory))
+				}
 			case "emotion":
 				emotionCategory = e.Payload
+				if ttsMode == "stream" {
+					ttsPool.Warm(streamConfig(voiceType, emotionCategory))
+				}
+			case "engine":
+				asrEngine = e.Payload
+				log.Debugf("识别引擎: %s", recognizeOptions().Engine)
 			case "audio_start":
 				log.Debug("main|收到录音开始事件...")
-				recorder.Start()
+				if listen.listening {
+					inChan <- tui.Event{Type: "record_error", Payload: "免提模式下请直接说话，输入 /handsfree 退出免提模式"}
+					break
+				}
+				startRecording()
 			case "audio_stop":
 				log.Debug("main|收到录音结束事件...")
 				recorder.Stop()
 
-				buf := recorder.Buffer()
-				log.Debug("正在识别语音输入...")
-				question := sendAudioToASR(asrClient, buf.Bytes())
-				log.Debugf("识别到内容：%s", question)
-				QA(client, question, inChan)
+				wav := recorder.Buffer().Bytes()
+				question, err := recognizeRecording(recognizer, wav, streamResult, inChan)
+				streamResult = nil
+				if err == nil && isStopCommand(question) {
+					stopVoiceMode()
+					break
+				}
+				askTranscript(client, wav, question, err, inChan)
+				// 连续对话：回答朗读完后自动开始下一次录音
+				if voiceMode == "continuous" && !asrConfirm && startRecording() {
+					inChan <- tui.Event{Type: "recording", Payload: "on"}
+				}
+			case "audio_file":
+				log.Debugf("main|收到音频文件: %s", e.Payload)
+				wav, err := audio.LoadFile(e.Payload)
+				var question string
+				if err == nil {
+					inChan <- tui.Event{Type: "notify", Payload: fmt.Sprintf("正在识别音频文件: %s", e.Payload)}
+					question, err = recognizeRecording(recognizer, wav, nil, inChan)
+				}
+				askTranscript(client, wav, question, err, inChan)
 			case "question":
 				log.Debug("main|收到输入问题事件...")
 				QA(client, e.Payload, inChan)
+			case "replay":
+				// 空内容表示重播最近的回答
+				message := -1
+				if e.Payload != "" {
+					message, _ = strconv.Atoi(e.Payload)
+				}
+				replayAnswer(message, inChan)
+			case "resynthesize":
+				message, _ := strconv.Atoi(e.Payload)
+				resynthesize(message, inChan)
+			case "lexicon_reload":
+				notice := "发音词典已重新加载"
+				if err := lexicon.Reload(); err != nil {
+					notice = fmt.Sprintf("加载发音词典失败: %v", err)
+				}
+				log.Debug(notice)
+				inChan <- tui.Event{Type: "notify", Payload: notice}
+			case "persona":
+				inChan <- selectPersona(e.Payload)
+			case "asr_confirm":
+				asrConfirm = !asrConfirm
+				notice := "已关闭识别确认，识别后直接发送"
+				if asrConfirm {
+					notice = "已开启识别确认，识别结果修改后按回车发送"
+				}
+				inChan <- tui.Event{Type: "notify", Payload: notice}
+			case "handsfree":
+				if voiceMode == "handsfree" {
+					stopVoiceMode()
+				} else {
+					stopVoiceMode()
+					voiceMode = "handsfree"
+					startListening()
+				}
+			case "continuous":
+				if voiceMode == "continuous" {
+					stopVoiceMode()
+				} else {
+					stopVoiceMode()
+					voiceMode = "continuous"
+					startContinuous()
+				}
+			case "wake":
+				if voiceMode == "wake" {
+					stopVoiceMode()
+				} else {
+					stopVoiceMode()
+					voiceMode = "wake"
+					startListening()
+					inChan <- tui.Event{Type: "notify", Payload: fmt.Sprintf("唤醒模式：说“%s”开始提问", wakePhrases[0])}
+				}
+			case "voice_stop":
+				stopVoiceMode()
 			}
 		}
-
-		log.Fatal("main|事件通道已关闭")
 	}()
 
-	p := tea.NewProgram(tui.InitialModel(log.StandardLogger(), eventChan, inChan), tea.WithAltScreen(), tea.WithMouseAllMotion())
+	// 启动时把音频文件作为第一个问题
+	if *audioFile != "" {
+		eventChan <- tui.Event{Type: "audio_file", Payload: *audioFile}
+	}
+
+	teaOpts := []tea.ProgramOption{tea.WithAltScreen(), tea.WithMouseAllMotion()}
+	if *sinkName == "stdout" {
+		// 标准输出留给语音数据
+		teaOpts = append(teaOpts, tea.WithOutput(os.Stderr))
+	}
+	p := tea.NewProgram(tui.InitialModel(log.StandardLogger(), eventChan, inChan, tui.WithLexiconFile(lexiconFile), tui.WithPushToTalk(pttKey, pttMode == "hold"), tui.WithPlaybackControls(controlChan)), teaOpts...)
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("出错了: %v", err)
 		return
@@ -173,14 +641,37 @@ func QA(c *openai.Client, request string, inChan chan tui.Event) {
 		log.Debug("*** 本次处理完成 ***")
 	}()
 
+	start := time.Now()
+	input := voiceInput
+	voiceInput = nil
+	var answer string
+	var speech []byte
+
 	// 构造新的用户提问, 并添加到历史记录中
 	newMessage := openai.ChatCompletionMessage{
 		Role:    openai.ChatMessageRoleUser,
 		Content: request,
 	}
 	history = append(history, newMessage)
+	answerIndex := len(history) // 本次回答在历史中的位置
+	if input != nil && input.corrected != "" && input.corrected == request {
+		// 提问确实加入历史后才告诉界面识别原文，确认模式下可能被放弃或者改掉
+		raw, _ := json.Marshal(tui.Transcript{Message: answerIndex - 1, Raw: input.transcript})
+		inChan <- tui.Event{Type: "transcript_raw", Payload: string(raw)}
+	}
+	cast := currentPersona
+
+	// 角色扮演的提示词只在请求时加上，不记录到历史中
+	msgs := history
+	if cast != nil && cast.Prompt != "" {
+		msgs = append([]openai.ChatCompletionMessage{{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: cast.Prompt,
+		}}, history...)
+	}
 
 	// 创建流式请求
+	streamChan := make(chan string, 1000)
 	textChan := make(chan string, 1000)
 	wholeChan := make(chan string, 1)
 
@@ -189,27 +680,35 @@ func QA(c *openai.Client, request string, inChan chan tui.Event) {
 	go func() {
 		defer wg.Done()
 		log.Warn("CompletionStream goroutine start...")
-		CompletionStream(c, modelName, history, textChan, wholeChan)
+		CompletionStream(c, modelName, msgs, streamChan, wholeChan)
 		log.Warn("✅CompletionStream goroutine exit")
 	}()
 
-	audioChan := make(chan []byte, 1000)
-	wg.Add(1)
+	// 边收边显示回答，开始朗读时界面上已经有这条回答，高亮才能从第一句开始
+	shown := append([]openai.ChatCompletionMessage(nil), history...)
+	relayed := make(chan struct{})
 	go func() {
-		defer wg.Done()
-		log.Warn("StreamTTS goroutine start...")
-		StreamTTS(voiceType, emotionCategory, textChan, audioChan)
-		log.Warn("✅StreamTTS goroutine exit")
-		close(audioChan)
+		defer close(relayed)
+		relayAnswer(streamChan, textChan, shown, inChan)
 	}()
 
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		log.Warn("PlayStreamAudio goroutine start...")
-		PlayStreamAudio(audioChan)
-		log.Warn("✅PlayStreamAudio goroutine exit")
-	}()
+	if tencentEnabled {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			log.Warn("speak goroutine start...")
+			speech = speak(voiceType, emotionCategory, cast, textChan, answerIndex, inChan)
+			log.Warn("✅speak goroutine exit")
+		}()
+	} else {
+		// 不朗读时只需要把AI的输出读完
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range textChan {
+			}
+		}()
+	}
 
 	// 记录到历史中
 	wg.Add(1)
@@ -217,7 +716,9 @@ func QA(c *openai.Client, request string, inChan chan tui.Event) {
 		defer wg.Done()
 		log.Debug("History goroutine start...")
 		resp := <-wholeChan
+		<-relayed // 完整的回答要在最后一次显示的部分回答之后
 		log.Debugf("resp: %s", resp)
+		answer = resp
 		history = append(history, openai.ChatCompletionMessage{
 			Role:    openai.ChatMessageRoleAssistant,
 			Content: resp,
@@ -234,6 +735,67 @@ func QA(c *openai.Client, request string, inChan chan tui.Event) {
 	wg.Wait()
 
 	log.Debug("✅✅✅✅等待所有goroutine完成✅✅✅✅")
+	if turnArchive != nil {
+		saveTurn(start, request, answer, input, speech, ttsCodec, cast)
+	}
+}
+
+// 边收边显示回答时刷新界面的最小间隔
+const answerRefresh = 200 * time.Millisecond
+
+// relayAnswer 把AI的输出转给朗读，同时把已经收到的部分回答显示在界面上，
+// 显示的间隔至少为answerRefresh。history为加上本次提问后的聊天历史
+func relayAnswer(streamChan <-chan string, textChan chan<- string, history []openai.ChatCompletionMessage, inChan chan tui.Event) {
+	defer close(textChan)
+
+	var answer strings.Builder
+	var shown time.Time
+	for text := range streamChan {
+		textChan <- text
+		answer.WriteString(text)
+		if strings.TrimSpace(answer.String()) == "" || time.Since(shown) < answerRefresh {
+			continue
+		}
+		shown = time.Now()
+		partial, _ := json.Marshal(append(history, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleAssistant,
+			Content: answer.String(),
+		}))
+		inChan <- tui.Event{Type: "history", Payload: string(partial)}
+	}
+}
+
+// selectPersona 切换角色扮演设定，名字为空时取消角色扮演。返回给界面的事件
+func selectPersona(name string) tui.Event {
+	if name == "" {
+		currentPersona = nil
+		return tui.Event{Type: "persona"}
+	}
+
+	p := persona.Find(personas, name)
+	if p == nil {
+		return tui.Event{Type: "notify", Payload: fmt.Sprintf("没有找到角色扮演设定: %s", name)}
+	}
+	currentPersona = p
+
+	payload, _ := json.Marshal(tui.Cast{Name: p.Name, Colors: p.Colors()})
+	return tui.Event{Type: "persona", Payload: string(payload)}
+}
+
+// speakingProgress 根据播放进度找到正在朗读的文字，变化时通知界面高亮
+// message为这次回答在聊天历史中的位置
+func speakingProgress(timeline *tts.Timeline, message int, inChan chan tui.Event) func(time.Duration) {
+	last := tts.Cue{Start: -1}
+	return func(pos time.Duration) {
+		cue, ok := timeline.At(pos)
+		if !ok || (cue.Start == last.Start && cue.Stop == last.Stop) {
+			return
+		}
+		last = cue
+
+		payload, _ := json.Marshal(tui.Speaking{Message: message, Start: cue.Start, Stop: cue.Stop})
+		inChan <- tui.Event{Type: "speaking", Payload: string(payload)}
+	}
 }
 
 // CompletionStream 调用AI流式回答
@@ -284,41 +846,135 @@ func CompletionStream(client *openai.Client, model string, msgs []openai.ChatCom
 	}
 }
 
+// sentenceSynthesizer 一句话一句话地合成语音
+type sentenceSynthesizer interface {
+	Run(text string, audioStream chan<- []byte)
+	Audio() []byte
+	Subtitles() []tts.Subtitle
+}
+
+// streamConfig 流式合成会话的参数
+func streamConfig(voiceType int64, emotionCategory string) tts.StreamConfig {
+	return tts.StreamConfig{
+		AppID:           appId,
+		SecretID:        secretId,
+		SecretKey:       secretKey,
+		VoiceType:       voiceType,
+		EmotionCategory: emotionCategory,
+		Speed:           speed,
+		Codec:           ttsCodec,
+	}
+}
+
+// sentence 待合成的一句话，offset为它在整个回答中的位置（按rune计）
+type sentence struct {
+	text      string
+	offset    int
+	lineStart bool // 是否在一行的开头，只有行首才可能有角色标签
+}
+
 // StreamTTS 语音合成
-// 读取textChan中的数据，将它以。分割，然后合成语音
-func StreamTTS(voiceType int64, emotionCategory string, textChan chan string, audioChan chan []byte) {
+// 读取textChan中的数据，将它以。和换行分割，然后合成语音
+// 每句合成完后把字幕追加到timeline中，用于播放时高亮正在朗读的文字
+// cast不为空时按角色扮演处理：每行开头的“角色：”决定用哪个声音读这一行
+// stop关闭后不再合成剩下的文字，只把textChan读完
+func StreamTTS(voiceType int64, emotionCategory string, cast *persona.Persona, textChan chan string, audioChan chan []byte, timeline *tts.Timeline, stop <-chan struct{}) {
+	base := 0 // buffer中第一个字在整个回答中的位置
+	if ttsMode == "stream" && cast == nil {
+		// 一个会话只能用一种声音，角色扮演时仍然一句话一个会话
+		rest, offset, err := StreamTTSSession(streamConfig(voiceType, emotionCategory), textChan, audioChan, timeline, stop)
+		if err == nil {
+			return
+		}
+		log.Warnf("流式合成失败，剩下的文字改为逐句合成: %v", err)
+		textChan, base = rest, offset
+	}
+
 	var buffer strings.Builder
+	lineStart := true // buffer是否从一行的开头开始
 
 	var wg sync.WaitGroup
 	wg.Add(1)
 
-	s := tts.NewRealTimeSpeechSynthesizer(int64(appId), secretId, secretKey, voiceType, emotionCategory, speed)
+	// 每种声音一个合成器，stream模式下使用预先建立好的会话
+	synthesizers := map[string]sentenceSynthesizer{}
+	synthesizer := func(voiceType int64, emotionCategory string) sentenceSynthesizer {
+		key := fmt.Sprintf("%d/%s", voiceType, emotionCategory)
+		if s, ok := synthesizers[key]; ok {
+			return s
+		}
+		var ss sentenceSynthesizer
+		if ttsMode == "stream" {
+			ss = ttsPool.Synthesizer(streamConfig(voiceType, emotionCategory), lexicon)
+		} else {
+			s := tts.NewRealTimeSpeechSynthesizer(int64(appId), secretId, secretKey, voiceType, emotionCategory, speed)
+			s.Lexicon = lexicon
+			s.SSML = ssml
+			s.Codec = ttsCodec
+			ss = s
+		}
+		synthesizers[key] = ss
+		return ss
+	}
 
-	sentenceChan := make(chan string)
+	sentenceChan := make(chan sentence)
 	// 启动一个 goroutine 来处理语音转换， 这样才能按顺序
 	go func() {
 		defer wg.Done()
 		index := 1
-		for sentence := range sentenceChan {
+		speaker := ""
+		for st := range sentenceChan {
+			voice, emotion := voiceType, emotionCategory
+			if cast != nil {
+				if st.lineStart {
+					speaker = cast.Default
+					if name, n, ok := cast.Speaker(st.text); ok {
+						speaker = name
+						st.text = string([]rune(st.text)[n:])
+						st.offset += n
+					}
+				}
+				if v, ok := cast.Voice(speaker); ok {
+					voice = v.VoiceType
+					if v.Emotion != "" {
+						emotion = v.Emotion
+					}
+				}
+			}
+			if strings.Trim(st.text, " 。") == "" {
+				continue
+			}
+			select {
+			case <-stop:
+				// 朗读被停止，剩下的句子不再合成，只是读完
+				continue
+			default:
+			}
+
 			log.Debug("----------------------------------")
-			log.Debugf("正在转换第[%d]段语音中，文字内容为:%s ", index, sentence)
-			s.Run(sentence, audioChan)
+			log.Debugf("正在转换第[%d]段语音中，角色:%s，文字内容为:%s ", index, speaker, st.text)
+			s := synthesizer(voice, emotion)
+			s.Run(st.text, audioChan)
+			duration, err := myplayer.Duration(ttsCodec, s.Audio())
+			if err != nil {
+				log.Warnf("计算第[%d]段语音时长失败: %v", index, err)
+			}
+			timeline.Append(st.offset, duration, s.Subtitles())
 			index++
 			log.Debug("----------------------------------")
 		}
 		log.Info("**语音转换全部结束！！**")
 	}()
 
-	index := 1
 	for {
 		select {
 		case resp, ok := <-textChan:
 			if !ok {
 				log.Debugf("TextChan closed, buf len:%d", buffer.Len())
 				// Channel 已关闭
-				if buffer.Len() > 0 {
+				if text := strings.TrimSpace(buffer.String()); text != "" {
 					// 发送句子到通道
-					sentenceChan <- strings.TrimSpace(buffer.String())
+					sentenceChan <- sentence{text: text, offset: base + leadingSpaces(buffer.String()), lineStart: lineStart}
 				}
 				goto END
 			}
@@ -326,29 +982,32 @@ func StreamTTS(voiceType int64, emotionCategory string, textChan chan string, au
 			// log.Debugf("Speech recv [%q]", resp)
 			buffer.WriteString(resp)
 
-			// 按句号分割句子
+			// 按句号和换行分割句子，最后一个句子可能是不完整的，留在 buffer 中
 			content := buffer.String()
-			sentences := strings.Split(content, "。")
-
-			// 重置 buffer
 			buffer.Reset()
-
-			for i, sentence := range sentences {
-				sentence = strings.TrimSpace(sentence)
-				if sentence == "" {
-					continue
+			for {
+				i := strings.IndexAny(content, "。\n")
+				if i < 0 {
+					buffer.WriteString(content)
+					break
 				}
 
-				if i == len(sentences)-1 && !strings.HasSuffix(content, "。") {
-					// 最后一个句子可能是不完整的，保存到 buffer 中
-					buffer.WriteString(sentence)
-				} else {
-					sentenceChan <- sentence + "。"
-					// log.Debugf("发送第[%d]句子到sentenceChan：%s", index, sentence)
-					index++
+				part := content[:i]
+				sep, size := utf8.DecodeRuneInString(content[i:])
+				if text := strings.TrimSpace(part); text != "" {
+					if sep == '。' {
+						text += "。"
+					}
+					sentenceChan <- sentence{text: text, offset: base + leadingSpaces(part), lineStart: lineStart}
+				}
+				base += utf8.RuneCountInString(part) + 1
+				if sep == '\n' {
+					lineStart = true
+				} else if strings.TrimSpace(part) != "" {
+					lineStart = false
 				}
+				content = content[i+size:]
 			}
-
 		}
 	}
 END:
@@ -358,34 +1017,268 @@ END:
 	wg.Wait()
 }
 
-// 语音识别
-func sendAudioToASR(c *asr.ASRClient, data []byte) string {
-	asrResult, err := c.ToVoice("wav", data)
+// StreamTTSSession 一轮对话使用一个流式合成会话，AI的输出边到边发给合成服务
+// 有发音词典时按短句发送，保证词典中的词不会被拆开。会话建立失败或中途断开时返回错误，
+// 以及还没有合成的文字rest和它在回答中的位置，由调用方逐句合成。
+// stop关闭时放弃会话，剩下的文字不再合成
+func StreamTTSSession(cfg tts.StreamConfig, textChan chan string, audioChan chan []byte, timeline *tts.Timeline, stop <-chan struct{}) (rest chan string, base int, err error) {
+	s, err := ttsPool.Get(cfg)
 	if err != nil {
-		return err.Error()
+		return textChan, 0, err
+	}
+	s.Lexicon = lexicon
+	s.Bind(audioChan, timeline)
+
+	// 等待服务端合成剩下的语音时也可能被停止
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		select {
+		case <-stop:
+			s.Close()
+		case <-finished:
+		}
+	}()
+
+	var pending strings.Builder
+	offset := 0 // pending中第一个字在整个回答中的位置
+	flush := func() error {
+		if pending.Len() == 0 {
+			return nil
+		}
+		if err := s.Send(pending.String(), offset); err != nil {
+			return err
+		}
+		// 标记句子的开始，用于跳到下一句
+		for i, r := range []rune(pending.String()) {
+			if strings.ContainsRune("。！？!?\n", r) {
+				timeline.MarkSentence(offset + i + 1)
+			}
+		}
+		offset += utf8.RuneCountInString(pending.String())
+		pending.Reset()
+		return nil
+	}
+	// lost 会话断开后，没发出去的文字和textChan中剩下的文字都交给调用方
+	lost := func(err error) (chan string, int, error) {
+		s.Close()
+		// 后面逐句合成的语音接在会话已经合成的语音之后
+		duration, derr := myplayer.Duration(ttsCodec, s.Audio())
+		if derr != nil {
+			log.Warnf("计算已合成语音时长失败: %v", derr)
+		}
+		timeline.Append(0, duration, nil)
+
+		rest := make(chan string)
+		go func() {
+			defer close(rest)
+			rest <- pending.String()
+			for text := range textChan {
+				rest <- text
+			}
+		}()
+		return rest, offset, err
+	}
+
+receive:
+	for {
+		select {
+		case text, ok := <-textChan:
+			if !ok {
+				break receive
+			}
+			pending.WriteString(text)
+			if lexicon.Empty() || strings.ContainsAny(text, "，。！？；：,.!?;:\n") {
+				if err := flush(); err != nil {
+					return lost(err)
+				}
+			}
+		case <-stop:
+			log.Debug("朗读被停止，不再合成剩下的文字")
+			for range textChan {
+			}
+			return nil, 0, nil
+		}
+	}
+	if err := flush(); err != nil {
+		return lost(err)
+	}
+
+	if err := s.Complete(); err != nil {
+		select {
+		case <-stop:
+			log.Debug("朗读被停止，放弃了合成会话")
+		default:
+			log.Errorf("流式合成失败: %v", err)
+		}
+	}
+	log.Info("**语音转换全部结束！！**")
+	return nil, 0, nil
+}
+
+// leadingSpaces 返回TrimSpace会去掉的开头空白字符个数
+func leadingSpaces(s string) int {
+	return utf8.RuneCountInString(s) - utf8.RuneCountInString(strings.TrimLeftFunc(s, unicode.IsSpace))
+}
+
+// recognizeRecording 识别一段WAV录音并检查结果。stream识别模式下识别结果从streamResult中取，
+// 否则用recognizer识别；录音太短、没有声音、识别失败或没有内容时返回错误
+func recognizeRecording(recognizer asr.Recognizer, wav []byte, streamResult <-chan recognition, inChan chan tui.Event) (string, error) {
+	var question string
+	err := asr.CheckRecording(wav)
+	if streamResult != nil {
+		// 实时识别的结果总要取出来，识别用的goroutine才能结束
+		r := <-streamResult
+		if err == nil {
+			question, err = r.text, r.err
+		}
+	} else if err == nil {
+		if asrPreprocess {
+			if processed, err := audio.Preprocess(wav, audio.DefaultPreprocess); err != nil {
+				log.Warnf("预处理录音失败，使用原始录音: %v", err)
+			} else {
+				log.Debugf("预处理录音: %d -> %d 字节", len(wav), len(processed))
+				wav = processed
+			}
+		}
+		log.Debug("正在识别语音输入...")
+		question, err = sendAudioToASR(recognizer, wav, recognizeOptions(), func(done, total int) {
+			inChan <- tui.Event{Type: "notify", Payload: fmt.Sprintf("录音较长，分%d段识别，已完成%d段...", total, done)}
+		})
+	}
+	if err != nil {
+		return "", err
+	}
+	return asr.CheckTranscript(question)
+}
+
+// askTranscript 把识别结果作为问题交给AI；识别失败时在界面上提示原因。
+// 配置了纠正模型时先纠正识别结果，确认模式下先填入输入框
+func askTranscript(c *openai.Client, wav []byte, question string, err error, inChan chan tui.Event) {
+	if err != nil {
+		log.Warnf("语音识别失败: %v", err)
+		inChan <- tui.Event{Type: "notify", Payload: fmt.Sprintf("语音识别失败: %v", err)}
+		return
+	}
+	log.Debugf("识别到内容：%s", question)
+	// 录音可能是录音机的缓冲区，下次录音时会被覆盖
+	voiceInput = &turnInput{wav: bytes.Clone(wav), transcript: question}
+
+	if corrector != nil {
+		corrected, err := corrector.Correct(question, history, asrOptions.Hotwords)
+		if err != nil {
+			log.Warnf("纠正识别结果失败: %v", err)
+		}
+		if corrected != question {
+			log.Debugf("纠正后的内容：%s", corrected)
+			voiceInput.corrected = corrected
+			question = corrected
+		}
+	}
+
+	if asrConfirm {
+		inChan <- tui.Event{Type: "confirm", Payload: question}
+		return
+	}
+	QA(c, question, inChan)
+}
+
+// 语音识别，较长的录音会分段识别，每识别完一段调用一次progress
+func sendAudioToASR(c asr.Recognizer, data []byte, opts asr.Options, progress func(done, total int)) (string, error) {
+	asrResult, err := asr.RecognizeLong(c, data, opts, progress)
+	if err != nil {
+		return "", err
 	}
 	// log.Debugf("voice to text: %v\n", asrResult)
-	return asrResult
+	return asrResult, nil
 }
 
-// 播放语音
-func PlayStreamAudio(audioStream chan []byte) {
+// recognition 一次识别的结果
+type recognition struct {
+	text string
+	err  error
+}
+
+// streamASR 边录音边识别，识别结果实时显示在输入框中，录音结束后把最终结果写入result
+func streamASR(c *asr.RealtimeClient, wav <-chan []byte, opts asr.Options, inChan chan tui.Event, result chan<- recognition) {
+	pcm := audio.PCMStream(wav, audio.Mono16k)
+	text, err := c.Recognize(pcm, opts, func(partial string) {
+		inChan <- tui.Event{Type: "transcript", Payload: partial}
+	})
+	if err != nil {
+		log.Errorf("实时语音识别失败: %v", err)
+		if text != "" {
+			// 已经识别出部分内容时，仍然使用这部分内容
+			err = nil
+		}
+	}
+	inChan <- tui.Event{Type: "transcript"}
+	result <- recognition{text: text, err: err}
+}
+
+// 播放codec编码的语音，播放过程中会定期把播放进度交给progress
+func PlayStreamAudio(audioStream chan []byte, codec string, timeline *tts.Timeline, progress func(time.Duration)) {
 	log.Debug("正在准备播放语音...")
-	player := myplayer.NewMyPlayer(audioStream)
-	player.Reset()
+	if _, err := myplayer.DecoderFor(codec); err != nil {
+		log.Errorf("无法播放语音: %v", err)
+		for range audioStream {
+		}
+		return
+	}
+	player := myplayer.NewMyPlayer(audioStream, myplayer.WithCodec(codec), myplayer.WithSink(audioSink))
+	nowPlaying.start(player, timeline)
+	defer nowPlaying.finish(player)
 
-	wg := sync.WaitGroup{}
-	wg.Add(2)
-	go func() {
-		defer wg.Done()
-		player.Play()
-		log.Debug("Player started")
-	}()
+	// 播放时定时报告进度，播放完后确保不会再报告
+	stopped := make(chan struct{})
 	go func() {
-		defer wg.Done()
-		player.GracefulStop()
-		log.Debug("GracefulStop finished")
+		defer close(stopped)
+		t := time.NewTicker(50 * time.Millisecond)
+		defer t.Stop()
+		for {
+			select {
+			case <-player.Done():
+				return
+			case <-t.C:
+				progress(player.Position())
+			}
+		}
 	}()
-	wg.Wait()
+	player.Play()
+	<-stopped
 	log.Debug("语音播放完成，播放器退出...")
 }
+
+// envOr 读取环境变量，未设置时返回默认值
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// envBool 读取布尔类型的环境变量，未设置或无法解析时为false
+func envBool(key string) bool {
+	b, _ := strconv.ParseBool(os.Getenv(key))
+	return b
+}
+
+// envDuration 读取时长类型的环境变量，如800ms，未设置或无法解析时为0
+func envDuration(key string) time.Duration {
+	d, _ := time.ParseDuration(os.Getenv(key))
+	return d
+}
+
+// envDurationOr 读取时长类型的环境变量，未设置或无法解析时为def
+func envDurationOr(key string, def time.Duration) time.Duration {
+	if d := envDuration(key); d > 0 {
+		return d
+	}
+	return def
+}
+
+// envFloat 读取浮点数类型的环境变量，未设置或无法解析时为0
+func envFloat(key string) float64 {
+	f, _ := strconv.ParseFloat(os.Getenv(key), 64)
+	return f
+}
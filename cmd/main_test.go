@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/tui"
+)
+
+func TestRelayAnswer(t *testing.T) {
+	history := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "你好"}}
+	streamChan := make(chan string, 3)
+	textChan := make(chan string, 3)
+	inChan := make(chan tui.Event, 3)
+	streamChan <- "你好"
+	streamChan <- "，"
+	streamChan <- "我是小智。"
+	close(streamChan)
+
+	relayAnswer(streamChan, textChan, history, inChan)
+
+	var text string
+	for s := range textChan {
+		text += s
+	}
+	if text != "你好，我是小智。" {
+		t.Errorf("text = %q", text)
+	}
+
+	// 第一段文字就显示出来，朗读的高亮从一开始就有地方显示
+	if len(inChan) != 1 {
+		t.Fatalf("got %d history events, want 1", len(inChan))
+	}
+	var shown []openai.ChatCompletionMessage
+	if err := json.Unmarshal([]byte((<-inChan).Payload), &shown); err != nil {
+		t.Fatal(err)
+	}
+	if len(shown) != 2 || shown[1].Role != openai.ChatMessageRoleAssistant || shown[1].Content != "你好" {
+		t.Errorf("shown = %+v", shown)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+	log "github.com/sirupsen/logrus"
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/persona"
+	myplayer "gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/player"
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/replay"
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/tts"
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/tui"
+)
+
+// playback 正在朗读的回答，界面的播放控制通过它操作播放器
+type playback struct {
+	mu       sync.Mutex
+	player   *myplayer.MyPlayer
+	timeline *tts.Timeline
+	volume   int // 音量百分比，换了回答也保持
+}
+
+var nowPlaying = &playback{volume: 100}
+
+// 每次调节音量的幅度（百分比）
+const volumeStep = 10
+
+// start 开始朗读一个回答
+func (p *playback) start(player *myplayer.MyPlayer, timeline *tts.Timeline) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	player.SetVolume(float64(p.volume) / 100)
+	p.player, p.timeline = player, timeline
+}
+
+// finish 回答朗读完了
+func (p *playback) finish(player *myplayer.MyPlayer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.player == player {
+		p.player, p.timeline = nil, nil
+	}
+}
+
+// control 执行界面的播放控制：pause 暂停/继续，next 跳到下一句，stop 停止朗读，
+// volume_up、volume_down 调节音量。返回给界面的提示
+func (p *playback) control(action string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	log.Debugf("播放控制: %s", action)
+
+	switch action {
+	case "volume_up", "volume_down":
+		if action == "volume_up" {
+			p.volume += volumeStep
+		} else {
+			p.volume -= volumeStep
+		}
+		if p.volume > 100 {
+			p.volume = 100
+		}
+		if p.volume < 0 {
+			p.volume = 0
+		}
+		if p.player != nil {
+			p.player.SetVolume(float64(p.volume) / 100)
+		}
+		return fmt.Sprintf("音量: %d%%", p.volume)
+	}
+
+	if p.player == nil {
+		return "当前没有在朗读"
+	}
+	switch action {
+	case "pause":
+		if p.player.Paused() {
+			p.player.Resume()
+			return "继续朗读"
+		}
+		p.player.Pause()
+		return "已暂停朗读"
+	case "next":
+		next, ok := p.timeline.NextSentence(p.player.Position())
+		if !ok {
+			return "已经是最后一句了"
+		}
+		p.player.SkipTo(next)
+		return "跳到下一句"
+	case "stop":
+		p.player.Stop()
+		return "已停止朗读"
+	}
+	return fmt.Sprintf("未知的播放控制: %s", action)
+}
+
+// answerStore 每个回答朗读的语音和字幕时间轴，按回答在聊天历史中的位置保存，用于重播
+var answerStore *replay.Store
+
+// speak 合成并朗读textChan中的文字，message为这些文字在聊天历史中的位置，
+// 朗读完后保存语音用于重播，并返回合成的语音
+func speak(voiceType int64, emotionCategory string, cast *persona.Persona, textChan chan string, message int, inChan chan tui.Event) []byte {
+	audioChan := make(chan []byte, 1000)
+	timeline := tts.NewTimeline()
+	stop := make(chan struct{}) // 播放结束后关闭，朗读被停止时剩下的文字不再合成
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		log.Warn("StreamTTS goroutine start...")
+		StreamTTS(voiceType, emotionCategory, cast, textChan, audioChan, timeline, stop)
+		log.Warn("✅StreamTTS goroutine exit")
+		close(audioChan)
+	}()
+
+	// 留一份语音用于存档和重播
+	var speech bytes.Buffer
+	tee, copied := teeAudio(audioChan, &speech)
+	PlayStreamAudio(tee, ttsCodec, timeline, speakingProgress(timeline, message, inChan))
+	// 正常播放完时语音已经全部合成，只有被停止时才会中断合成
+	close(stop)
+	inChan <- tui.Event{Type: "speaking"}
+	wg.Wait()
+	<-copied
+
+	if speech.Len() > 0 {
+		if err := answerStore.Put(message, ttsCodec, speech.Bytes(), timeline); err != nil {
+			log.Warnf("保存重播用的语音失败: %v", err)
+		}
+	}
+	return speech.Bytes()
+}
+
+// replayAnswer 重播第message个聊天记录的语音，不用重新合成；message为-1时重播最近的回答
+func replayAnswer(message int, inChan chan tui.Event) {
+	notice := fmt.Sprintf("重播第%d条记录", message+1)
+	if message < 0 {
+		last, ok := answerStore.Last()
+		if !ok {
+			inChan <- tui.Event{Type: "notify", Payload: "还没有可以重播的回答"}
+			return
+		}
+		message, notice = last, "重播上一个回答"
+	}
+
+	audio, codec, err := answerStore.Get(message)
+	if err != nil {
+		log.Warnf("取出第%d条记录的语音失败: %v", message, err)
+		inChan <- tui.Event{Type: "notify", Payload: "这个回答没有保存语音，按 r 用当前的音色重新合成"}
+		return
+	}
+	inChan <- tui.Event{Type: "notify", Payload: notice}
+
+	audioChan := make(chan []byte, 1)
+	audioChan <- audio
+	close(audioChan)
+	timeline := answerStore.Timeline(message)
+	PlayStreamAudio(audioChan, codec, timeline, speakingProgress(timeline, message, inChan))
+	inChan <- tui.Event{Type: "speaking"}
+}
+
+// resynthesize 用当前选中的音色和情感重新合成并朗读第message个聊天记录，之后重播使用新的语音
+func resynthesize(message int, inChan chan tui.Event) {
+	if !tencentEnabled {
+		inChan <- tui.Event{Type: "notify", Payload: "没有配置腾讯云，不能合成语音"}
+		return
+	}
+	if message < 0 || message >= len(history) || history[message].Role != openai.ChatMessageRoleAssistant {
+		inChan <- tui.Event{Type: "notify", Payload: "请先在聊天历史中选择一个回答"}
+		return
+	}
+	inChan <- tui.Event{Type: "notify", Payload: fmt.Sprintf("用音色%d重新合成第%d条记录", voiceType, message+1)}
+
+	textChan := make(chan string, 1)
+	textChan <- history[message].Content
+	close(textChan)
+	speak(voiceType, emotionCategory, currentPersona, textChan, message, inChan)
+}
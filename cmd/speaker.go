@@ -0,0 +1,9 @@
+//go:build !nospeaker && (cgo || darwin || windows)
+
+package main
+
+import "gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/speaker"
+
+func init() {
+	newSpeaker = speaker.New
+}
@@ -0,0 +1,110 @@
+package archive
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 每轮对话目录中的文件名
+const (
+	ManifestFile = "manifest.json"
+	InputFile    = "input.wav"
+	answerFile   = "answer" // 加上音频编码作为扩展名，如answer.mp3
+)
+
+// Turn 一轮对话的存档清单，保存为目录中的manifest.json
+type Turn struct {
+	Time       time.Time `json:"time"`
+	Question   string    `json:"question"`             // 发送给AI的提问
+	Transcript string    `json:"transcript,omitempty"` // 纠正前的识别结果，打字提问时为空
+	Answer     string    `json:"answer"`
+
+	// 这轮对话时的设置
+	Model     string `json:"model"`
+	Voice     int64  `json:"voice,omitempty"`
+	Emotion   string `json:"emotion,omitempty"`
+	Persona   string `json:"persona,omitempty"`
+	ASREngine string `json:"asr_engine,omitempty"`
+
+	// 目录中的音频文件名，没有时为空
+	Input      string `json:"input,omitempty"` // 提问的录音
+	Audio      string `json:"audio,omitempty"` // 朗读回答的音频
+	AudioCodec string `json:"audio_codec,omitempty"`
+}
+
+// Archive 对话存档目录，每轮对话一个子目录
+type Archive struct {
+	dir string
+}
+
+// New 使用dir作为存档目录，不存在时创建
+func New(dir string) (*Archive, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Archive{dir: dir}, nil
+}
+
+// Save 把一轮对话存到一个新的子目录，目录名为对话的时间，返回目录的路径。
+// input为提问的WAV录音，audio为codec编码的回答音频，为空时不保存
+func (a *Archive) Save(t Turn, input, audio []byte, codec string) (string, error) {
+	dir, err := a.mkdir(t.Time)
+	if err != nil {
+		return "", err
+	}
+
+	if len(input) > 0 {
+		t.Input = InputFile
+		if err := os.WriteFile(filepath.Join(dir, t.Input), input, 0o644); err != nil {
+			return dir, err
+		}
+	}
+	if len(audio) > 0 {
+		t.Audio, t.AudioCodec = answerFile+"."+codec, codec
+		if err := os.WriteFile(filepath.Join(dir, t.Audio), audio, 0o644); err != nil {
+			return dir, err
+		}
+	}
+
+	manifest, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return dir, err
+	}
+	return dir, os.WriteFile(filepath.Join(dir, ManifestFile), manifest, 0o644)
+}
+
+// mkdir 创建以时间命名的子目录，同一秒内有多轮对话时加上序号
+func (a *Archive) mkdir(t time.Time) (string, error) {
+	name := t.Format("20060102-150405")
+	for i := 1; ; i++ {
+		dir := filepath.Join(a.dir, name)
+		if i > 1 {
+			dir = fmt.Sprintf("%s-%d", dir, i)
+		}
+		err := os.Mkdir(dir, 0o755)
+		if err == nil {
+			return dir, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return "", err
+		}
+	}
+}
+
+// Load 读取一轮对话的存档清单，音频文件在dir中，文件名见Input和Audio
+func Load(dir string) (Turn, error) {
+	var t Turn
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return t, err
+	}
+	if err := json.Unmarshal(data, &t); err != nil {
+		return t, fmt.Errorf("解析%s失败: %w", ManifestFile, err)
+	}
+	return t, nil
+}
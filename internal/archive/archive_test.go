@@ -0,0 +1,76 @@
+package archive
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSaveLoad(t *testing.T) {
+	a, err := New(filepath.Join(t.TempDir(), "archive"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	turn := Turn{
+		Time:       time.Date(2024, 6, 1, 12, 30, 0, 0, time.Local),
+		Question:   "今天天气怎么样",
+		Transcript: "今天天汽怎么样",
+		Answer:     "今天是晴天。",
+		Model:      "gpt-4o",
+		Voice:      101016,
+		Emotion:    "happy",
+		ASREngine:  "16k_zh",
+	}
+	input, audio := []byte("RIFF...."), []byte("ID3....")
+	dir, err := a.Save(turn, input, audio, "mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(dir) != "20240601-123000" {
+		t.Errorf("dir = %s", dir)
+	}
+
+	got, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := turn
+	want.Input, want.Audio, want.AudioCodec = "input.wav", "answer.mp3", "mp3"
+	if !got.Time.Equal(want.Time) {
+		t.Errorf("time = %v, want %v", got.Time, want.Time)
+	}
+	got.Time = want.Time
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Load() = %+v, want %+v", got, want)
+	}
+
+	for name, data := range map[string][]byte{got.Input: input, got.Audio: audio} {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || !bytes.Equal(b, data) {
+			t.Errorf("%s = %q, %v", name, b, err)
+		}
+	}
+
+	// 同一秒内的下一轮对话，没有录音和音频
+	dir2, err := a.Save(Turn{Time: turn.Time, Question: "谢谢"}, nil, nil, "mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(dir2) != "20240601-123000-2" {
+		t.Errorf("second dir = %s", dir2)
+	}
+	got2, err := Load(dir2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got2.Input != "" || got2.Audio != "" {
+		t.Errorf("second turn has audio: %+v", got2)
+	}
+	if entries, _ := os.ReadDir(dir2); len(entries) != 1 {
+		t.Errorf("second dir has %d files, want only the manifest", len(entries))
+	}
+}
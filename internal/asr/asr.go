@@ -26,12 +26,24 @@ func NewClient(c *common.Credential) (*ASRClient, error) {
 
 // 将音频内容转为文本返回，出错返回err
 func (a *ASRClient) ToVoice(fileType string, fileContents []byte) (string, error) {
+	return a.Recognize(fileType, fileContents, Options{})
+}
+
+// Recognize 实现Recognizer，使用一句话识别
+func (a *ASRClient) Recognize(fileType string, fileContents []byte, opts Options) (string, error) {
 	request := asr.NewSentenceRecognitionRequest()
 
 	// 设置上传本地音频文件
 	request.SourceType = common.Uint64Ptr(1)
 	request.VoiceFormat = common.StringPtr(fileType)
-	request.EngSerViceType = common.StringPtr("16k_zh")
+	request.EngSerViceType = common.StringPtr(opts.engine())
+	request.ConvertNumMode = common.Int64Ptr(opts.convertNumMode())
+	request.FilterDirty = common.Int64Ptr(boolInt(opts.FilterDirty))
+	request.FilterModal = common.Int64Ptr(boolInt(opts.FilterModal))
+	request.FilterPunc = common.Int64Ptr(boolInt(opts.FilterPunc))
+	if hotwords := opts.hotwordList(); hotwords != "" {
+		request.HotwordList = common.StringPtr(hotwords)
+	}
 
 	// 将buf的内容base64编码后设置给request.Data
 	d64 := base64.StdEncoding.EncodeToString(fileContents)
@@ -0,0 +1,82 @@
+package asr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const correctPrompt = `你是语音识别结果的校对助手。根据最近的对话和热词，修正识别结果中的同音错字、专有名词和明显的断句错误。
+不要改写句意，不要回答其中的问题，不要添加解释。只输出纠正后的文字，没有需要修正的地方时原样输出。`
+
+// 纠正时参考的最近几条对话
+const correctHistorySize = 6
+
+// 纠正的最长等待时间，超时后使用原文
+const correctTimeout = 10 * time.Second
+
+// Corrector 让一个小而快的模型根据对话上下文和热词纠正识别结果，识别错的专有名词往往从上下文就能看出来
+type Corrector struct {
+	client *openai.Client
+	model  string
+}
+
+func NewCorrector(client *openai.Client, model string) *Corrector {
+	return &Corrector{client: client, model: model}
+}
+
+// Correct 返回纠正后的文字，出错时返回原文和错误
+func (c *Corrector) Correct(text string, history []openai.ChatCompletionMessage, hotwords []string) (string, error) {
+	if len(history) > correctHistorySize {
+		history = history[len(history)-correctHistorySize:]
+	}
+
+	var b strings.Builder
+	if words := whisperPrompt(hotwords); words != "" {
+		fmt.Fprintf(&b, "热词：%s\n", words)
+	}
+	if len(history) > 0 {
+		b.WriteString("最近的对话：\n")
+		for _, m := range history {
+			role := "AI"
+			if m.Role == openai.ChatMessageRoleUser {
+				role = "用户"
+			}
+			fmt.Fprintf(&b, "%s：%s\n", role, m.Content)
+		}
+	}
+	fmt.Fprintf(&b, "识别结果：%s", text)
+
+	ctx, cancel := context.WithTimeout(context.Background(), correctTimeout)
+	defer cancel()
+	resp, err := c.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: c.model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: correctPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: b.String()},
+		},
+		Temperature: 0,
+	})
+	if err != nil {
+		return text, err
+	}
+	if len(resp.Choices) == 0 {
+		return text, errors.New("纠正结果为空")
+	}
+
+	corrected := strings.TrimSpace(resp.Choices[0].Message.Content)
+	corrected = strings.TrimPrefix(corrected, "识别结果：")
+	if corrected == "" {
+		return text, errors.New("纠正结果为空")
+	}
+	// 模型偶尔会回答问题而不是纠正，长度差别太大时不采用
+	if n := utf8.RuneCountInString(text); utf8.RuneCountInString(corrected) > 2*n+10 {
+		return text, fmt.Errorf("纠正结果和原文差别太大: %s", corrected)
+	}
+	return corrected, nil
+}
@@ -0,0 +1,58 @@
+package asr
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// fakeChatServer 本地模拟的对话接口，检查请求后返回reply
+func fakeChatServer(t *testing.T, reply string) *openai.Client {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if req.Model != "fast" || len(req.Messages) != 2 {
+			t.Errorf("model = %q, %d messages", req.Model, len(req.Messages))
+		}
+		prompt := req.Messages[1].Content
+		for _, want := range []string{"热词：Kubernetes", "用户：什么是容器编排", "识别结果：酷伯内提斯怎么部署"} {
+			if !strings.Contains(prompt, want) {
+				t.Errorf("prompt %q does not contain %q", prompt, want)
+			}
+		}
+		json.NewEncoder(w).Encode(openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{
+			Message: openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: reply},
+		}}})
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := openai.DefaultConfig("key")
+	cfg.BaseURL = srv.URL + "/v1"
+	return openai.NewClientWithConfig(cfg)
+}
+
+func TestCorrect(t *testing.T) {
+	history := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleUser, Content: "什么是容器编排"},
+		{Role: openai.ChatMessageRoleAssistant, Content: "比如Kubernetes。"},
+	}
+	raw := "酷伯内提斯怎么部署"
+
+	c := NewCorrector(fakeChatServer(t, " Kubernetes怎么部署\n"), "fast")
+	got, err := c.Correct(raw, history, []string{"Kubernetes|11"})
+	if err != nil || got != "Kubernetes怎么部署" {
+		t.Errorf("got %q, %v", got, err)
+	}
+
+	// 模型回答了问题而不是纠正时，使用原文
+	c = NewCorrector(fakeChatServer(t, strings.Repeat("先安装kubectl，然后创建集群。", 5)), "fast")
+	if got, err := c.Correct(raw, history, []string{"Kubernetes"}); err == nil || got != raw {
+		t.Errorf("got %q, %v; want original text and an error", got, err)
+	}
+}
@@ -0,0 +1,62 @@
+package asr
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/audio"
+)
+
+const (
+	// 一句话识别最长支持60秒，留一点余量
+	SentenceLimit = 55 * time.Second
+	// 一句话识别的数据最多3MB，44.1k、48k双声道的录音不到60秒就会超出
+	SentenceSizeLimit = 3 << 20
+	// 超过TaskThreshold的录音，如果后端支持，使用录音文件识别
+	TaskThreshold = 3 * time.Minute
+	// 录音文件识别上传本地数据最多5MB，16k单声道大约2分40秒
+	TaskLimit = 150 * time.Second
+)
+
+// TaskRecognizer 支持异步录音文件识别的后端，适合很长的录音
+type TaskRecognizer interface {
+	RecognizeTask(format string, data []byte, opts Options) (string, error)
+}
+
+// RecognizeLong 识别任意长度的WAV录音。不超过SentenceLimit和SentenceSizeLimit时直接识别；
+// 否则转为16k单声道，仍然太长的在静音处切成几段，按顺序识别后拼接，
+// 很长时如果r支持，每段用录音文件识别。每识别完一段调用一次progress
+func RecognizeLong(r Recognizer, wav []byte, opts Options, progress func(done, total int)) (string, error) {
+	f, pcm, err := audio.ParseWAV(wav)
+	if err != nil {
+		return "", err
+	}
+	duration := f.Duration(len(pcm))
+	if duration <= SentenceLimit && len(wav) <= SentenceSizeLimit {
+		return r.Recognize("wav", wav, opts)
+	}
+
+	pcm = audio.NewConverter(f, audio.Mono16k).Convert(pcm)
+	recognize, limit := r.Recognize, SentenceLimit
+	if tr, ok := r.(TaskRecognizer); ok && duration > TaskThreshold {
+		recognize, limit = tr.RecognizeTask, TaskLimit
+	}
+
+	chunks := audio.SplitAtSilence(audio.Mono16k, pcm, limit)
+	texts := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		if progress != nil {
+			progress(i, len(chunks))
+		}
+		text, err := recognize("wav", append(audio.WAVHeader(audio.Mono16k, len(chunk)), chunk...), opts)
+		if err != nil {
+			return strings.Join(texts, ""), fmt.Errorf("第%d/%d段: %w", i+1, len(chunks), err)
+		}
+		texts = append(texts, text)
+	}
+	if progress != nil {
+		progress(len(chunks), len(chunks))
+	}
+	return strings.Join(texts, ""), nil
+}
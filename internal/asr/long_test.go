@@ -0,0 +1,90 @@
+package asr
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/audio"
+)
+
+// fakeRecognizer 把每段录音“识别”为它的时长
+type fakeRecognizer struct {
+	calls int
+}
+
+func (r *fakeRecognizer) Recognize(format string, data []byte, opts Options) (string, error) {
+	r.calls++
+	f, pcm, err := audio.ParseWAV(data)
+	if err != nil {
+		return "", err
+	}
+	if d := f.Duration(len(pcm)); d > SentenceLimit {
+		return "", fmt.Errorf("too long: %v", d)
+	}
+	if len(data) > SentenceSizeLimit {
+		return "", fmt.Errorf("too large: %d bytes", len(data))
+	}
+	return fmt.Sprintf("[%d]", r.calls), nil
+}
+
+func TestRecognizeLong(t *testing.T) {
+	r := &fakeRecognizer{}
+	var progress []string
+	text, err := RecognizeLong(r, wav(70*time.Second, 3000), Options{}, func(done, total int) {
+		progress = append(progress, fmt.Sprintf("%d/%d", done, total))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "[1][2]" {
+		t.Errorf("text = %q", text)
+	}
+	if fmt.Sprint(progress) != "[0/2 1/2 2/2]" {
+		t.Errorf("progress = %v", progress)
+	}
+
+	// 短录音直接识别，不报告进度
+	r, progress = &fakeRecognizer{}, nil
+	if text, err := RecognizeLong(r, wav(3*time.Second, 3000), Options{}, func(done, total int) {
+		progress = append(progress, fmt.Sprintf("%d/%d", done, total))
+	}); err != nil || text != "[1]" || progress != nil {
+		t.Errorf("short: %q, %v, %v", text, err, progress)
+	}
+
+	// 48k双声道的录音不到一分钟就超过3MB，转为16k单声道后识别
+	stereo := audio.Format{SampleRate: 48000, Channels: 2, BitsPerSample: 16}
+	pcm := make([]byte, stereo.BytesPerSecond()*20)
+	r = &fakeRecognizer{}
+	if text, err := RecognizeLong(r, append(audio.WAVHeader(stereo, len(pcm)), pcm...), Options{}, nil); err != nil || text != "[1]" {
+		t.Errorf("large: %q, %v", text, err)
+	}
+}
+
+func TestRecTaskRequest(t *testing.T) {
+	// 很长的录音走录音文件识别，同样要带上热词
+	r := newRecTaskRequest([]byte("RIFF..."), Options{Engine: "16k_zh", Hotwords: []string{"天气", "小智|11"}})
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["HotwordList"] != "天气|10,小智|11" || body["EngineModelType"] != "16k_zh" {
+		t.Errorf("request = %v", body)
+	}
+	if data, _ := json.Marshal(newRecTaskRequest(nil, Options{})); strings.Contains(string(data), "HotwordList") {
+		t.Errorf("request without hotwords = %s", data)
+	}
+}
+
+func TestParseTaskResult(t *testing.T) {
+	result := "[0:0.020,0:2.380]  今天天气怎么样？\n[0:2.380,0:5.100]  适合出去玩吗？\n"
+	if got := parseTaskResult(result); got != "今天天气怎么样？适合出去玩吗？" {
+		t.Errorf("got %q", got)
+	}
+}
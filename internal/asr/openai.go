@@ -0,0 +1,72 @@
+package asr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// OpenAIClient 通过OpenAI兼容的 /v1/audio/transcriptions 接口（Whisper）识别语音，
+// 只有OpenAI兼容网关时也能使用语音输入
+type OpenAIClient struct {
+	client *openai.Client
+	model  string
+}
+
+// NewOpenAIClient 创建OpenAI兼容的语音识别客户端，model为空时使用whisper-1
+func NewOpenAIClient(client *openai.Client, model string) *OpenAIClient {
+	if model == "" {
+		model = openai.Whisper1
+	}
+	return &OpenAIClient{
+		client: client,
+		model:  model,
+	}
+}
+
+// Recognize 以multipart方式上传录音，返回识别结果。
+// 引擎对应到Whisper的语言，热词作为提示词传给模型；数字转换和过滤选项不支持，会被忽略
+func (o *OpenAIClient) Recognize(format string, data []byte, opts Options) (string, error) {
+	resp, err := o.client.CreateTranscription(context.Background(), openai.AudioRequest{
+		Model:    o.model,
+		FilePath: "audio." + format, // 服务端根据文件名判断格式
+		Reader:   bytes.NewReader(data),
+		Format:   openai.AudioResponseFormatJSON,
+		Language: whisperLanguage(opts.Engine),
+		Prompt:   whisperPrompt(opts.Hotwords),
+	})
+	if err != nil {
+		return "", fmt.Errorf("fileType:%v, len:%v, err:%w", format, len(data), err)
+	}
+	return resp.Text, nil
+}
+
+// whisperLanguage 把识别引擎对应到ISO-639-1语言代码，混合语种等无法对应时返回空，由模型自动判断
+func whisperLanguage(engine string) string {
+	switch engine {
+	case "", "16k_zh", "16k_yue", "16k_zh_dialect":
+		return "zh"
+	case "16k_en":
+		return "en"
+	case "16k_ja":
+		return "ja"
+	case "16k_ko":
+		return "ko"
+	}
+	return ""
+}
+
+// whisperPrompt 热词去掉权重后拼成提示词
+func whisperPrompt(hotwords []string) string {
+	words := make([]string, 0, len(hotwords))
+	for _, w := range hotwords {
+		w, _, _ = strings.Cut(w, "|")
+		if w = strings.TrimSpace(w); w != "" {
+			words = append(words, w)
+		}
+	}
+	return strings.Join(words, "，")
+}
@@ -0,0 +1,48 @@
+package asr
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestOpenAIRecognize(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/transcriptions" {
+			http.NotFound(w, r)
+			return
+		}
+		if model := r.FormValue("model"); model != "whisper-large" {
+			t.Errorf("model = %q", model)
+		}
+		if lang, prompt := r.FormValue("language"), r.FormValue("prompt"); lang != "en" || prompt != "Kubernetes，Tencent" {
+			t.Errorf("language = %q, prompt = %q", lang, prompt)
+		}
+		f, header, err := r.FormFile("file")
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(f)
+		if header.Filename != "audio.wav" || string(data) != "RIFF..." {
+			t.Errorf("file = %q, %q", header.Filename, data)
+		}
+		json.NewEncoder(w).Encode(map[string]string{"text": "今天天气怎么样"})
+	}))
+	defer srv.Close()
+
+	cfg := openai.DefaultConfig("key")
+	cfg.BaseURL = srv.URL + "/v1"
+	var r Recognizer = NewOpenAIClient(openai.NewClientWithConfig(cfg), "whisper-large")
+
+	text, err := r.Recognize("wav", []byte("RIFF..."), Options{Engine: "16k_en", Hotwords: []string{"Kubernetes|11", "Tencent"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "今天天气怎么样" {
+		t.Errorf("text = %q", text)
+	}
+}
@@ -0,0 +1,129 @@
+package asr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 引擎为空时使用的默认引擎
+const DefaultEngine = "16k_zh"
+
+// 数字转换方式
+const (
+	NumAuto = "auto" // 根据场景智能转换为阿拉伯数字
+	NumNone = "none" // 不转换，直接输出中文数字
+	NumMath = "math" // 按数学公式转换，如“百分之五”转为“5%”
+)
+
+// Engine 识别引擎（语种）
+type Engine struct {
+	ID       string
+	Name     string
+	Language string // 对应音色目录中的语言，为空表示没有对应的音色语言
+}
+
+// Engines 可选的识别引擎
+var Engines = []Engine{
+	{ID: "16k_zh", Name: "中文普通话", Language: "中文"},
+	{ID: "16k_zh-PY", Name: "中英粤混合"},
+	{ID: "16k_en", Name: "英文", Language: "英文"},
+	{ID: "16k_yue", Name: "粤语", Language: "粤语"},
+	{ID: "16k_zh_dialect", Name: "多方言", Language: "四川话"},
+	{ID: "16k_ja", Name: "日语"},
+	{ID: "16k_ko", Name: "韩语"},
+}
+
+// LookupEngine 按ID查找引擎
+func LookupEngine(id string) (Engine, bool) {
+	for _, e := range Engines {
+		if e.ID == id {
+			return e, true
+		}
+	}
+	return Engine{}, false
+}
+
+// EngineForLanguage 根据音色的语言选择识别引擎，没有对应引擎时返回DefaultEngine
+func EngineForLanguage(language string) string {
+	for _, e := range Engines {
+		if e.Language != "" && e.Language == language {
+			return e.ID
+		}
+	}
+	return DefaultEngine
+}
+
+// Options 识别参数，零值即为默认设置：普通话引擎、智能转换数字、不过滤
+type Options struct {
+	Engine   string   // 引擎，为空时为DefaultEngine
+	Hotwords []string // 热词，提高专有名词的识别率，可写成“词|权重”，权重默认为10
+
+	ConvertNum  string // 数字转换方式，为空时为NumAuto
+	FilterDirty bool   // 过滤脏词
+	FilterModal bool   // 过滤语气词
+	FilterPunc  bool   // 过滤句末的句号
+}
+
+func (o Options) engine() string {
+	if o.Engine == "" {
+		return DefaultEngine
+	}
+	return o.Engine
+}
+
+// convertNumMode 腾讯云的数字转换参数：0不转换，1智能转换，3数学转换
+func (o Options) convertNumMode() int64 {
+	switch o.ConvertNum {
+	case NumNone:
+		return 0
+	case NumMath:
+		return 3
+	default:
+		return 1
+	}
+}
+
+// hotwordList 腾讯云的临时热词表格式：“词|10,词|11”
+func (o Options) hotwordList() string {
+	words := make([]string, 0, len(o.Hotwords))
+	for _, w := range o.Hotwords {
+		w = strings.TrimSpace(w)
+		if w == "" {
+			continue
+		}
+		if !strings.Contains(w, "|") {
+			w += "|10"
+		}
+		words = append(words, w)
+	}
+	return strings.Join(words, ",")
+}
+
+// ParseHotwords 解析逗号或换行分隔的热词列表
+func ParseHotwords(s string) []string {
+	var words []string
+	for _, w := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '，' || r == '\n' }) {
+		if w = strings.TrimSpace(w); w != "" {
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+// ParseConvertNum 校验数字转换方式，空字符串视为NumAuto
+func ParseConvertNum(s string) (string, error) {
+	switch s {
+	case "", NumAuto:
+		return NumAuto, nil
+	case NumNone, NumMath:
+		return s, nil
+	}
+	return "", fmt.Errorf("未知的数字转换方式 %q，可选 auto、none、math", s)
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
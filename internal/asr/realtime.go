@@ -0,0 +1,203 @@
+package asr
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+)
+
+// 腾讯云实时语音识别的接口地址，后面还要加上AppId
+const DefaultRealtimeEndpoint = "wss://asr.cloud.tencent.com/asr/v2/"
+
+// RealtimeConfig 实时语音识别的参数
+type RealtimeConfig struct {
+	AppID     int64
+	SecretID  string
+	SecretKey string
+	Endpoint  string // 为空时为DefaultRealtimeEndpoint，测试时可指向本地服务
+}
+
+type realtimeResult struct {
+	SliceType    int    `json:"slice_type"` // 0:一句话开始 1:识别中 2:一句话结束
+	Index        int    `json:"index"`
+	VoiceTextStr string `json:"voice_text_str"`
+}
+
+type realtimeResponse struct {
+	Code    int            `json:"code"`
+	Message string         `json:"message"`
+	VoiceID string         `json:"voice_id"`
+	Final   int            `json:"final"`
+	Result  realtimeResult `json:"result"`
+}
+
+// RealtimeClient 实时语音识别：边录音边上传，识别结果随时更新，录音结束后很快就能拿到最终结果
+type RealtimeClient struct {
+	cfg RealtimeConfig
+}
+
+func NewRealtimeClient(cfg RealtimeConfig) *RealtimeClient {
+	return &RealtimeClient{cfg: cfg}
+}
+
+// Recognize 把16k单声道16位PCM数据流发送给识别服务，直到pcm关闭。
+// 识别结果每次变化时调用onPartial，返回最终的识别结果
+func (c *RealtimeClient) Recognize(pcm <-chan []byte, opts Options, onPartial func(string)) (string, error) {
+	voiceID := uuid.New().String()
+	u, err := c.url(voiceID, opts)
+	if err != nil {
+		return "", err
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(u, nil)
+	if err != nil {
+		// 把剩下的录音读完，避免录音程序阻塞
+		for range pcm {
+		}
+		return "", fmt.Errorf("voice_id: %s, dial: %w", voiceID, err)
+	}
+	defer conn.Close()
+
+	var resp realtimeResponse
+	if err := conn.ReadJSON(&resp); err != nil {
+		for range pcm {
+		}
+		return "", fmt.Errorf("voice_id: %s, handshake: %w", voiceID, err)
+	}
+	if resp.Code != 0 {
+		for range pcm {
+		}
+		return "", fmt.Errorf("voice_id: %s, code: %d, message: %s", voiceID, resp.Code, resp.Message)
+	}
+
+	// 发送录音，结束后告诉服务端
+	var sendErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for data := range pcm {
+			if sendErr != nil {
+				continue
+			}
+			sendErr = conn.WriteMessage(websocket.BinaryMessage, data)
+		}
+		if sendErr == nil {
+			sendErr = conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"end"}`))
+		}
+	}()
+
+	// 接收识别结果，按句子序号拼接
+	sentences := map[int]string{}
+	var recvErr error
+	for {
+		var resp realtimeResponse
+		if err := conn.ReadJSON(&resp); err != nil {
+			recvErr = fmt.Errorf("voice_id: %s, %w", voiceID, err)
+			break
+		}
+		if resp.Code != 0 {
+			recvErr = fmt.Errorf("voice_id: %s, code: %d, message: %s", voiceID, resp.Code, resp.Message)
+			break
+		}
+		if resp.Final == 1 {
+			break
+		}
+
+		if prev, ok := sentences[resp.Result.Index]; !ok || prev != resp.Result.VoiceTextStr {
+			sentences[resp.Result.Index] = resp.Result.VoiceTextStr
+			if onPartial != nil {
+				onPartial(joinSentences(sentences))
+			}
+		}
+	}
+
+	if recvErr != nil {
+		// 让发送的goroutine尽快结束
+		conn.Close()
+	}
+	wg.Wait()
+	if recvErr != nil {
+		return joinSentences(sentences), recvErr
+	}
+	if sendErr != nil {
+		log.Warnf("发送录音失败: %v", sendErr)
+	}
+	return joinSentences(sentences), nil
+}
+
+func joinSentences(sentences map[int]string) string {
+	indexes := make([]int, 0, len(sentences))
+	for i := range sentences {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	var b strings.Builder
+	for _, i := range indexes {
+		b.WriteString(sentences[i])
+	}
+	return b.String()
+}
+
+// url 生成带签名的地址：对 host+path+?+排序后的参数 做HmacSha1
+func (c *RealtimeClient) url(voiceID string, opts Options) (string, error) {
+	endpoint := c.cfg.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultRealtimeEndpoint
+	}
+	u, err := url.Parse(endpoint + strconv.FormatInt(c.cfg.AppID, 10))
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().Unix()
+	params := map[string]string{
+		"secretid":          c.cfg.SecretID,
+		"timestamp":         strconv.FormatInt(now, 10),
+		"expired":           strconv.FormatInt(now+24*60*60, 10),
+		"nonce":             strconv.Itoa(rand.Intn(1e9)),
+		"engine_model_type": opts.engine(),
+		"voice_id":          voiceID,
+		"voice_format":      "1", // pcm
+		"needvad":           "1",
+		"convert_num_mode":  strconv.FormatInt(opts.convertNumMode(), 10),
+		"filter_dirty":      strconv.FormatInt(boolInt(opts.FilterDirty), 10),
+		"filter_modal":      strconv.FormatInt(boolInt(opts.FilterModal), 10),
+		"filter_punc":       strconv.FormatInt(boolInt(opts.FilterPunc), 10),
+	}
+	if hotwords := opts.hotwordList(); hotwords != "" {
+		params["hotword_list"] = hotwords
+	}
+
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	// 签名用原始参数，请求地址中的参数要转义（热词里有中文和“|”）
+	var plain, escaped []string
+	for _, k := range keys {
+		plain = append(plain, k+"="+params[k])
+		escaped = append(escaped, k+"="+url.QueryEscape(params[k]))
+	}
+
+	mac := hmac.New(sha1.New, []byte(c.cfg.SecretKey))
+	mac.Write([]byte(u.Host + u.Path + "?" + strings.Join(plain, "&")))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	u.RawQuery = strings.Join(escaped, "&") + "&signature=" + url.QueryEscape(signature)
+	return u.String(), nil
+}
@@ -0,0 +1,111 @@
+package asr
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeRealtimeServer 本地模拟的实时识别服务：每收到一块录音就多“识别”出一个字，
+// 每三块结束一句话，收到结束消息后返回final
+func fakeRealtimeServer(t *testing.T) RealtimeConfig {
+	words := []rune("今天天气怎么样")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("signature") == "" || q.Get("voice_format") != "1" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if q.Get("engine_model_type") != "16k_zh" || q.Get("hotword_list") != "天气|10,小智|11" {
+			conn.WriteJSON(realtimeResponse{Code: 4001, Message: "参数错误: " + r.URL.RawQuery})
+			return
+		}
+		conn.WriteJSON(realtimeResponse{Message: "success"})
+
+		chunks, index, text := 0, 0, ""
+		for {
+			typ, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if typ == websocket.TextMessage && strings.Contains(string(data), "end") {
+				conn.WriteJSON(realtimeResponse{Result: realtimeResult{SliceType: 2, Index: index, VoiceTextStr: text}})
+				conn.WriteJSON(realtimeResponse{Final: 1})
+				return
+			}
+
+			text += string(words[chunks%len(words)])
+			chunks++
+			res := realtimeResult{SliceType: 1, Index: index, VoiceTextStr: text}
+			if chunks%3 == 0 {
+				res.SliceType = 2
+				index++
+				text = ""
+			}
+			conn.WriteJSON(realtimeResponse{Result: res})
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return RealtimeConfig{
+		AppID:     1,
+		SecretID:  "id",
+		SecretKey: "key",
+		Endpoint:  "ws" + strings.TrimPrefix(srv.URL, "http") + "/asr/v2/",
+	}
+}
+
+func TestRealtimeRecognize(t *testing.T) {
+	c := NewRealtimeClient(fakeRealtimeServer(t))
+
+	pcm := make(chan []byte)
+	go func() {
+		for i := 0; i < 7; i++ {
+			pcm <- make([]byte, 1280)
+		}
+		close(pcm)
+	}()
+
+	var partials []string
+	text, err := c.Recognize(pcm, Options{Hotwords: []string{"天气", "小智|11"}}, func(s string) { partials = append(partials, s) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "今天天气怎么样" {
+		t.Errorf("text = %q", text)
+	}
+	if len(partials) != 7 || partials[0] != "今" || partials[3] != "今天天气" {
+		t.Errorf("partials = %v", partials)
+	}
+}
+
+func TestRealtimeRecognizeError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteJSON(realtimeResponse{Code: 4002, Message: "鉴权失败"})
+	}))
+	defer srv.Close()
+
+	c := NewRealtimeClient(RealtimeConfig{Endpoint: "ws" + strings.TrimPrefix(srv.URL, "http") + "/asr/v2/"})
+	pcm := make(chan []byte, 3)
+	for i := 0; i < 3; i++ {
+		pcm <- []byte(fmt.Sprint(i))
+	}
+	close(pcm)
+
+	if _, err := c.Recognize(pcm, Options{}, nil); err == nil || !strings.Contains(err.Error(), "4002") {
+		t.Errorf("err = %v, want code 4002", err)
+	}
+}
@@ -0,0 +1,6 @@
+package asr
+
+// Recognizer 把一段完整的录音转为文字，format为录音的格式，如wav、mp3
+type Recognizer interface {
+	Recognize(format string, data []byte, opts Options) (string, error)
+}
@@ -0,0 +1,91 @@
+package asr
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	asr "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/asr/v20190614"
+	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+)
+
+// 录音文件识别的结果每句话前面带有时间戳，如“[0:0.020,0:2.380]  ”
+var timestampPrefix = regexp.MustCompile(`(?m)^\[[^\]]*\]\s*`)
+
+// 查询录音文件识别结果的间隔和最长等待时间
+const (
+	taskPollInterval = time.Second
+	taskTimeout      = 10 * time.Minute
+)
+
+// recTaskRequest 录音文件识别接口支持临时热词，但目前使用的SDK版本的请求中还没有HotwordList
+type recTaskRequest struct {
+	*asr.CreateRecTaskRequest
+	HotwordList *string `json:"HotwordList,omitempty" name:"HotwordList"`
+}
+
+// RecognizeTask 实现TaskRecognizer，创建录音文件识别任务并等待结果
+func (a *ASRClient) RecognizeTask(fileType string, fileContents []byte, opts Options) (string, error) {
+	response := asr.NewCreateRecTaskResponse()
+	if err := a.client.Send(newRecTaskRequest(fileContents, opts), response); err != nil {
+		return "", fmt.Errorf("fileType:%v, len:%v, err:%w", fileType, len(fileContents), err)
+	}
+	taskID := *response.Response.Data.TaskId
+
+	status := asr.NewDescribeTaskStatusRequest()
+	status.TaskId = common.Uint64Ptr(taskID)
+	deadline := time.Now().Add(taskTimeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(taskPollInterval)
+		resp, err := a.client.DescribeTaskStatus(status)
+		if err != nil {
+			return "", fmt.Errorf("task:%v, err:%w", taskID, err)
+		}
+		data := resp.Response.Data
+		switch *data.Status {
+		case 2: // 成功
+			return parseTaskResult(stringValue(data.Result)), nil
+		case 3: // 失败
+			return "", fmt.Errorf("task:%v, err:%s", taskID, stringValue(data.ErrorMsg))
+		}
+	}
+	return "", fmt.Errorf("task:%v, 等待识别结果超时", taskID)
+}
+
+// newRecTaskRequest 创建上传本地数据的录音文件识别请求，参数和一句话识别一致
+func newRecTaskRequest(fileContents []byte, opts Options) *recTaskRequest {
+	request := asr.NewCreateRecTaskRequest()
+	request.EngineModelType = common.StringPtr(opts.engine())
+	request.ChannelNum = common.Uint64Ptr(1)
+	request.ResTextFormat = common.Uint64Ptr(0)
+	request.SourceType = common.Uint64Ptr(1)
+	request.ConvertNumMode = common.Int64Ptr(opts.convertNumMode())
+	request.FilterDirty = common.Int64Ptr(boolInt(opts.FilterDirty))
+	request.FilterModal = common.Int64Ptr(boolInt(opts.FilterModal))
+	request.FilterPunc = common.Int64Ptr(boolInt(opts.FilterPunc))
+
+	d64 := base64.StdEncoding.EncodeToString(fileContents)
+	request.Data = common.StringPtr(d64)
+	request.DataLen = common.Uint64Ptr(uint64(len(fileContents)))
+
+	r := &recTaskRequest{CreateRecTaskRequest: request}
+	if hotwords := opts.hotwordList(); hotwords != "" {
+		r.HotwordList = common.StringPtr(hotwords)
+	}
+	return r
+}
+
+// parseTaskResult 去掉每句话前面的时间戳，拼成一段文字
+func parseTaskResult(result string) string {
+	result = timestampPrefix.ReplaceAllString(result, "")
+	return strings.ReplaceAll(strings.TrimSpace(result), "\n", "")
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
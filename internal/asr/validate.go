@@ -0,0 +1,59 @@
+package asr
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/audio"
+)
+
+// 短于MinDuration的录音一般是误触，不去识别
+const MinDuration = 500 * time.Millisecond
+
+// 录音中最大的采样值低于silencePeak时，认为没有录到声音
+const silencePeak = 200
+
+var (
+	ErrTooShort = errors.New("录音太短")
+	ErrSilent   = errors.New("没有录到声音")
+	ErrNoSpeech = errors.New("没有识别到内容")
+)
+
+// CheckRecording 识别前检查录音，录音太短或者没有声音时返回错误
+func CheckRecording(wav []byte) error {
+	f, pcm, err := audio.ParseWAV(wav)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSilent, err)
+	}
+	if d := f.Duration(len(pcm)); d < MinDuration {
+		return fmt.Errorf("%w: %v", ErrTooShort, d.Round(time.Millisecond))
+	}
+
+	peak := 0
+	for i := 0; i+1 < len(pcm); i += 2 {
+		v := int(int16(binary.LittleEndian.Uint16(pcm[i:])))
+		if v < 0 {
+			v = -v
+		}
+		if v > peak {
+			peak = v
+		}
+	}
+	if peak < silencePeak {
+		return ErrSilent
+	}
+	return nil
+}
+
+// CheckTranscript 检查识别结果，去掉首尾空白后返回；只有标点符号时视为没有识别到内容
+func CheckTranscript(text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if strings.IndexFunc(text, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) < 0 {
+		return "", ErrNoSpeech
+	}
+	return text, nil
+}
@@ -0,0 +1,53 @@
+package asr
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/audio"
+)
+
+// wav 生成时长为d、振幅为amplitude的方波录音
+func wav(d time.Duration, amplitude int16) []byte {
+	n := int(d.Seconds() * 16000)
+	pcm := make([]byte, n*2)
+	for i := 0; i < n; i++ {
+		v := amplitude
+		if i/40%2 == 1 {
+			v = -v
+		}
+		binary.LittleEndian.PutUint16(pcm[i*2:], uint16(v))
+	}
+	return append(audio.WAVHeader(audio.Mono16k, len(pcm)), pcm...)
+}
+
+func TestCheckRecording(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"normal", wav(2*time.Second, 3000), nil},
+		{"too short", wav(200*time.Millisecond, 3000), ErrTooShort},
+		{"silent", wav(2*time.Second, 20), ErrSilent},
+		{"empty", nil, ErrSilent},
+	}
+	for _, tt := range tests {
+		if err := CheckRecording(tt.data); !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestCheckTranscript(t *testing.T) {
+	if text, err := CheckTranscript("  今天天气怎么样？\n"); err != nil || text != "今天天气怎么样？" {
+		t.Errorf("got %q, %v", text, err)
+	}
+	for _, s := range []string{"", "  ", "。", "？！"} {
+		if _, err := CheckTranscript(s); !errors.Is(err, ErrNoSpeech) {
+			t.Errorf("CheckTranscript(%q) err = %v", s, err)
+		}
+	}
+}
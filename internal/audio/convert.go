@@ -0,0 +1,161 @@
+package audio
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// Converter 把任意采样率、声道数的16位PCM转换为另一种格式，可以分块连续处理。
+// 降低采样率时先低通滤波，避免高于新采样率一半的频率混叠成噪声
+type Converter struct {
+	from, to Format
+
+	pos     float64   // 下一个输出采样在当前块中的位置
+	prev    float64   // 上一块最后一个采样，插值时使用
+	rest    []byte    // 上一块中不足一帧的数据
+	taps    []float64 // 低通滤波器系数，不降低采样率时为空
+	history []float64 // 上一块最后len(taps)-1个采样，滤波时使用
+}
+
+func NewConverter(from, to Format) *Converter {
+	c := &Converter{from: from, to: to}
+	if to.SampleRate < from.SampleRate {
+		c.taps = lowPass(0.45*float64(to.SampleRate)/float64(from.SampleRate), lowPassTaps)
+		c.history = make([]float64, len(c.taps)-1)
+	}
+	return c
+}
+
+// lowPassTaps 低通滤波器的长度，48k降到16k时过渡带约2.5kHz
+const lowPassTaps = 63
+
+// lowPass 截止频率为cutoff（相对于采样率）的加汉明窗的sinc低通滤波器
+func lowPass(cutoff float64, n int) []float64 {
+	taps := make([]float64, n)
+	var sum float64
+	for i := range taps {
+		x := float64(i) - float64(n-1)/2
+		v := 2 * cutoff
+		if x != 0 {
+			v = math.Sin(2*math.Pi*cutoff*x) / (math.Pi * x)
+		}
+		v *= 0.54 - 0.46*math.Cos(2*math.Pi*float64(i)/float64(n-1))
+		taps[i] = v
+		sum += v
+	}
+	for i := range taps {
+		taps[i] /= sum
+	}
+	return taps
+}
+
+// filter 对这一块单声道采样低通滤波，接着上一块继续
+func (c *Converter) filter(mono []float64) []float64 {
+	if c.taps == nil || len(mono) == 0 {
+		return mono
+	}
+	in := append(c.history, mono...)
+	out := make([]float64, len(mono))
+	for i := range out {
+		var v float64
+		for k, t := range c.taps {
+			v += t * in[i+len(c.taps)-1-k]
+		}
+		out[i] = v
+	}
+	c.history = append([]float64(nil), in[len(in)-len(c.history):]...)
+	return out
+}
+
+// Convert 转换一块数据，返回转换后的数据
+func (c *Converter) Convert(data []byte) []byte {
+	frameSize := c.from.Channels * 2
+	data = append(c.rest, data...)
+	n := len(data) / frameSize
+	c.rest = append([]byte(nil), data[n*frameSize:]...)
+
+	// 混合为单声道
+	mono := make([]float64, n)
+	for i := 0; i < n; i++ {
+		sum := 0
+		for ch := 0; ch < c.from.Channels; ch++ {
+			sum += int(int16(binary.LittleEndian.Uint16(data[i*frameSize+ch*2:])))
+		}
+		mono[i] = float64(sum) / float64(c.from.Channels)
+	}
+	mono = c.filter(mono)
+
+	// 线性插值重采样，位置-1为上一块的最后一个采样
+	step := float64(c.from.SampleRate) / float64(c.to.SampleRate)
+	out := make([]byte, 0, int(float64(n)/step+1)*c.to.Channels*2)
+	for {
+		i := int(math.Floor(c.pos))
+		if i+1 >= n {
+			break
+		}
+		a := c.prev
+		if i >= 0 {
+			a = mono[i]
+		}
+		v := a + (mono[i+1]-a)*(c.pos-float64(i))
+		s := uint16(clamp16(v))
+		for ch := 0; ch < c.to.Channels; ch++ {
+			out = binary.LittleEndian.AppendUint16(out, s)
+		}
+		c.pos += step
+	}
+	if n > 0 {
+		c.pos -= float64(n)
+		c.prev = mono[n-1]
+	}
+	return out
+}
+
+func clamp16(v float64) int16 {
+	if v > 32767 {
+		return 32767
+	}
+	if v < -32768 {
+		return -32768
+	}
+	return int16(v)
+}
+
+// PCMStream 把WAV数据流转换为指定格式的PCM数据流，输入关闭后输出也会关闭
+func PCMStream(wav <-chan []byte, to Format) <-chan []byte {
+	out := make(chan []byte, 64)
+	pr, pw := io.Pipe()
+
+	go func() {
+		for data := range wav {
+			pw.Write(data)
+		}
+		pw.Close()
+	}()
+
+	go func() {
+		defer close(out)
+		defer pr.Close()
+
+		from, err := ReadWAVHeader(pr)
+		if err != nil {
+			io.Copy(io.Discard, pr)
+			return
+		}
+		c := NewConverter(from, to)
+		buf := make([]byte, 4096)
+		for {
+			n, err := pr.Read(buf)
+			if n > 0 {
+				if pcm := c.Convert(buf[:n]); len(pcm) > 0 {
+					out <- pcm
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,47 @@
+package audio
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestConverterLowPass(t *testing.T) {
+	// 48k降到16k：1kHz的声音不受影响，10kHz的声音超过8kHz，应该被滤掉而不是混叠成6kHz
+	from := Format{SampleRate: 48000, Channels: 2, BitsPerSample: 16}
+	for _, tt := range []struct {
+		freq    float64
+		min     float64
+		max     float64
+		comment string
+	}{
+		{1000, 8000 * 0.95, 8000 * 1.05, "passband"},
+		{10000, 0, 8000 * 0.05, "above 8kHz"},
+	} {
+		out := NewConverter(from, Mono16k).Convert(tone(from, tt.freq, 8000, time.Second))
+		// 跳过开头滤波器还没有填满的部分
+		if peak, _ := peakRMS(out[200:]); peak < tt.min || peak > tt.max {
+			t.Errorf("%s: %vHz peak = %v, want in [%v, %v]", tt.comment, tt.freq, peak, tt.min, tt.max)
+		}
+	}
+}
+
+func TestConverterChunks(t *testing.T) {
+	// 分块转换和一次转换的结果相同
+	from := Format{SampleRate: 44100, Channels: 2, BitsPerSample: 16}
+	pcm := tone(from, 440, 8000, 500*time.Millisecond)
+	whole := NewConverter(from, Mono16k).Convert(pcm)
+
+	c := NewConverter(from, Mono16k)
+	var chunked []byte
+	for i := 0; i < len(pcm); i += 1001 {
+		end := i + 1001
+		if end > len(pcm) {
+			end = len(pcm)
+		}
+		chunked = append(chunked, c.Convert(pcm[i:end])...)
+	}
+	if !bytes.Equal(chunked, whole) {
+		t.Errorf("chunked conversion differs: %d vs %d bytes", len(chunked), len(whole))
+	}
+}
@@ -0,0 +1,46 @@
+package audio
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/hajimehoshi/go-mp3"
+)
+
+// LoadFile 读取WAV或MP3文件，转换为识别服务需要的16k单声道WAV
+func LoadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(data)
+}
+
+// Decode 根据文件头判断是WAV还是MP3，解码后转换为16k单声道WAV
+func Decode(data []byte) ([]byte, error) {
+	var f Format
+	var pcm []byte
+	if bytes.HasPrefix(data, []byte("RIFF")) {
+		var err error
+		if f, pcm, err = ParseWAV(data); err != nil {
+			return nil, fmt.Errorf("解析WAV失败: %w", err)
+		}
+	} else {
+		d, err := mp3.NewDecoder(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("不支持的音频格式，只支持WAV和MP3: %w", err)
+		}
+		if pcm, err = io.ReadAll(d); err != nil {
+			return nil, fmt.Errorf("解码MP3失败: %w", err)
+		}
+		// go-mp3总是输出16位双声道
+		f = Format{SampleRate: d.SampleRate(), Channels: 2, BitsPerSample: 16}
+	}
+
+	if f != Mono16k {
+		pcm = NewConverter(f, Mono16k).Convert(pcm)
+	}
+	return append(WAVHeader(Mono16k, len(pcm)), pcm...), nil
+}
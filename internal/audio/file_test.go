@@ -0,0 +1,31 @@
+package audio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecodeWAV(t *testing.T) {
+	// 1秒44.1k双声道的静音
+	from := Format{SampleRate: 44100, Channels: 2, BitsPerSample: 16}
+	data := append(WAVHeader(from, from.BytesPerSecond()), make([]byte, from.BytesPerSecond())...)
+
+	wav, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, pcm, err := ParseWAV(wav)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f != Mono16k {
+		t.Errorf("format = %+v", f)
+	}
+	if d := f.Duration(len(pcm)); d < 990*time.Millisecond || d > time.Second {
+		t.Errorf("duration = %v", d)
+	}
+
+	if _, err := Decode([]byte("not audio")); err == nil {
+		t.Error("want error for unknown format")
+	}
+}
@@ -0,0 +1,189 @@
+package audio
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+	"time"
+)
+
+// PreprocessConfig 识别前预处理录音的参数
+type PreprocessConfig struct {
+	TargetPeak float64       // 归一化后的峰值（0~1），为0时不调整音量
+	MaxGain    float64       // 最多放大几倍，避免把很小的声音连同噪声一起放得太大
+	Trim       bool          // 去掉首尾的静音
+	TrimPad    time.Duration // 去掉静音时首尾保留的长度，避免截掉字的开头和结尾
+	Gate       bool          // 噪声门：压低说话间隙中的背景噪声
+	GateFloor  float64       // 噪声门关闭时的增益
+}
+
+var DefaultPreprocess = PreprocessConfig{
+	TargetPeak: 0.9,
+	MaxGain:    8,
+	Trim:       true,
+	TrimPad:    200 * time.Millisecond,
+	Gate:       true,
+	GateFloor:  0.1,
+}
+
+const (
+	// 按10ms一帧判断有没有声音
+	preprocessFrame = 10 * time.Millisecond
+	// 有声音的帧前后这么久也算有声音，噪声门不会切掉字的开头和尾音
+	gateHold = 150 * time.Millisecond
+	// 有声音的音量至少是背景噪声的noiseRatio倍，并且均方根振幅不低于minLevel
+	noiseRatio = 3
+	minLevel   = 300
+	// 最响的帧不到最安静的帧的minRange倍时，录音中没有停顿，安静的帧也是在说话
+	minRange = 10
+)
+
+// Preprocess 把WAV录音处理为适合识别的16k单声道WAV：混合声道、重采样、
+// 去掉首尾静音、压低说话间隙的噪声并把音量归一化
+func Preprocess(wav []byte, cfg PreprocessConfig) ([]byte, error) {
+	f, pcm, err := ParseWAV(wav)
+	if err != nil {
+		return nil, err
+	}
+	if f != Mono16k {
+		pcm = NewConverter(f, Mono16k).Convert(pcm)
+	}
+
+	samples := make([]float64, len(pcm)/2)
+	for i := range samples {
+		samples[i] = float64(int16(binary.LittleEndian.Uint16(pcm[i*2:])))
+	}
+	samples = preprocess(samples, cfg)
+
+	out := WAVHeader(Mono16k, len(samples)*2)
+	for _, v := range samples {
+		out = binary.LittleEndian.AppendUint16(out, uint16(clamp16(math.Round(v))))
+	}
+	return out, nil
+}
+
+func preprocess(samples []float64, cfg PreprocessConfig) []float64 {
+	frame := int(preprocessFrame.Seconds() * float64(Mono16k.SampleRate))
+	loud := loudFrames(samples, frame)
+
+	// 整段都没有声音时不去静音，也不做噪声门
+	first, last := -1, -1
+	for i, l := range loud {
+		if l {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+
+	if first >= 0 && cfg.Gate {
+		hold := int(gateHold / preprocessFrame)
+		open := make([]bool, len(loud))
+		for i, l := range loud {
+			if !l {
+				continue
+			}
+			for j := i - hold; j <= i+hold; j++ {
+				if j >= 0 && j < len(open) {
+					open[j] = true
+				}
+			}
+		}
+		gate(samples, frame, open, cfg.GateFloor)
+	}
+
+	if first >= 0 && cfg.Trim {
+		pad := int(cfg.TrimPad.Seconds() * float64(Mono16k.SampleRate))
+		start := first*frame - pad
+		if start < 0 {
+			start = 0
+		}
+		end := (last+1)*frame + pad
+		if end > len(samples) {
+			end = len(samples)
+		}
+		samples = samples[start:end]
+	}
+
+	if cfg.TargetPeak > 0 {
+		normalize(samples, cfg.TargetPeak*32767, cfg.MaxGain)
+	}
+	return samples
+}
+
+// loudFrames 逐帧判断有没有声音。背景噪声取所有帧中较安静的那部分的音量，
+// 一直在说话、没有明显比说话安静的帧时，较安静的部分也是说话，所有帧都算有声音
+func loudFrames(samples []float64, frame int) []bool {
+	n := (len(samples) + frame - 1) / frame
+	rms := make([]float64, n)
+	for i := range rms {
+		end := (i + 1) * frame
+		if end > len(samples) {
+			end = len(samples)
+		}
+		var sum float64
+		for _, v := range samples[i*frame : end] {
+			sum += v * v
+		}
+		rms[i] = math.Sqrt(sum / float64(end-i*frame))
+	}
+	if n == 0 {
+		return nil
+	}
+
+	sorted := append([]float64(nil), rms...)
+	sort.Float64s(sorted)
+	noise, speech := sorted[n/10], sorted[n*9/10]
+	threshold := math.Max(minLevel, noise*noiseRatio)
+	if speech >= minLevel && speech < noise*minRange {
+		threshold = 0
+	}
+
+	loud := make([]bool, n)
+	for i, v := range rms {
+		loud[i] = v >= threshold
+	}
+	return loud
+}
+
+// gate 关闭的帧乘以floor，开关之间在一帧内线性过渡，避免咔哒声
+func gate(samples []float64, frame int, open []bool, floor float64) {
+	prev := 1.0
+	if len(open) > 0 && !open[0] {
+		prev = floor
+	}
+	for i, o := range open {
+		gain := floor
+		if o {
+			gain = 1
+		}
+		end := (i + 1) * frame
+		if end > len(samples) {
+			end = len(samples)
+		}
+		length := float64(end - i*frame)
+		for j := i * frame; j < end; j++ {
+			samples[j] *= prev + (gain-prev)*float64(j-i*frame+1)/length
+		}
+		prev = gain
+	}
+}
+
+// normalize 把峰值调整到target，放大时最多放大maxGain倍
+func normalize(samples []float64, target, maxGain float64) {
+	peak := 0.0
+	for _, v := range samples {
+		peak = math.Max(peak, math.Abs(v))
+	}
+	if peak == 0 {
+		return
+	}
+	gain := target / peak
+	if maxGain > 0 && gain > maxGain {
+		gain = maxGain
+	}
+	for i := range samples {
+		samples[i] *= gain
+	}
+}
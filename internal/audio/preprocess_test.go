@@ -0,0 +1,126 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+// tone 生成f格式的正弦波，每个声道相同，amplitude为0时生成静音
+func tone(f Format, freq, amplitude float64, d time.Duration) []byte {
+	var pcm []byte
+	for i := 0; i < int(d.Seconds()*float64(f.SampleRate)); i++ {
+		v := int16(amplitude * math.Sin(2*math.Pi*freq*float64(i)/float64(f.SampleRate)))
+		for ch := 0; ch < f.Channels; ch++ {
+			pcm = binary.LittleEndian.AppendUint16(pcm, uint16(v))
+		}
+	}
+	return pcm
+}
+
+// peakRMS 16位PCM的峰值和均方根振幅
+func peakRMS(pcm []byte) (peak, rms float64) {
+	n := len(pcm) / 2
+	for i := 0; i < n; i++ {
+		v := float64(int16(binary.LittleEndian.Uint16(pcm[i*2:])))
+		peak = math.Max(peak, math.Abs(v))
+		rms += v * v
+	}
+	return peak, math.Sqrt(rms / float64(n))
+}
+
+func preprocessPCM(t *testing.T, f Format, pcm []byte, cfg PreprocessConfig) []byte {
+	t.Helper()
+	out, err := Preprocess(append(WAVHeader(f, len(pcm)), pcm...), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	of, opcm, err := ParseWAV(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if of != Mono16k {
+		t.Fatalf("format = %+v, want Mono16k", of)
+	}
+	return opcm
+}
+
+func TestPreprocess(t *testing.T) {
+	// 44.1k立体声：0.5秒静音、1秒说话、0.5秒静音
+	f := Format{SampleRate: 44100, Channels: 2, BitsPerSample: 16}
+	var pcm []byte
+	pcm = append(pcm, tone(f, 0, 0, 500*time.Millisecond)...)
+	pcm = append(pcm, tone(f, 440, 4000, time.Second)...)
+	pcm = append(pcm, tone(f, 0, 0, 500*time.Millisecond)...)
+
+	out := preprocessPCM(t, f, pcm, DefaultPreprocess)
+
+	// 首尾各保留TrimPad
+	want := time.Second + 2*DefaultPreprocess.TrimPad
+	if d := Mono16k.Duration(len(out)); d < want-20*time.Millisecond || d > want+20*time.Millisecond {
+		t.Errorf("duration = %v, want about %v", d, want)
+	}
+	if peak, _ := peakRMS(out); math.Abs(peak-0.9*32767) > 0.01*32767 {
+		t.Errorf("peak = %v, want about %v", peak, 0.9*32767)
+	}
+}
+
+func TestPreprocessMaxGain(t *testing.T) {
+	out := preprocessPCM(t, Mono16k, tone(Mono16k, 440, 1000, time.Second), DefaultPreprocess)
+	if peak, _ := peakRMS(out); math.Abs(peak-8000) > 80 {
+		t.Errorf("peak = %v, want about 8000 (limited by MaxGain)", peak)
+	}
+}
+
+func TestPreprocessGate(t *testing.T) {
+	// 说话之间有1秒轻微的噪声
+	var pcm []byte
+	pcm = append(pcm, tone(Mono16k, 440, 8000, 500*time.Millisecond)...)
+	pcm = append(pcm, tone(Mono16k, 3000, 100, time.Second)...)
+	pcm = append(pcm, tone(Mono16k, 440, 8000, 500*time.Millisecond)...)
+
+	out := preprocessPCM(t, Mono16k, pcm, PreprocessConfig{Gate: true, GateFloor: 0.1})
+	if len(out) != len(pcm) {
+		t.Fatalf("length = %d, want %d", len(out), len(pcm))
+	}
+
+	// 噪声中间（离说话超过gateHold）被压低到十分之一，说话不受影响
+	middle := func(b []byte) []byte { return b[11200*2 : 20800*2] } // 0.7~1.3秒
+	_, before := peakRMS(middle(pcm))
+	_, after := peakRMS(middle(out))
+	if math.Abs(after-before*0.1) > before*0.01 {
+		t.Errorf("gap rms = %v, want about %v", after, before*0.1)
+	}
+	if _, rms := peakRMS(out[:16000]); math.Abs(rms-8000/math.Sqrt2) > 50 {
+		t.Errorf("speech rms = %v, want about %v", rms, 8000/math.Sqrt2)
+	}
+}
+
+func TestPreprocessContinuousSpeech(t *testing.T) {
+	// 没有停顿、音量时大时小的说话，小声的部分不能被当成噪声压低或者切掉
+	var pcm []byte
+	for _, amplitude := range []float64{8000, 2000, 4000, 1500, 8000, 3000} {
+		pcm = append(pcm, tone(Mono16k, 440, amplitude, 500*time.Millisecond)...)
+	}
+
+	out := preprocessPCM(t, Mono16k, pcm, PreprocessConfig{Trim: true, Gate: true, GateFloor: 0.1})
+	if len(out) != len(pcm) {
+		t.Fatalf("length = %d, want %d", len(out), len(pcm))
+	}
+	if !bytes.Equal(out, pcm) {
+		t.Error("continuous speech was gated")
+	}
+}
+
+func TestPreprocessSilent(t *testing.T) {
+	pcm := tone(Mono16k, 0, 0, time.Second)
+	out := preprocessPCM(t, Mono16k, pcm, DefaultPreprocess)
+	if len(out) != len(pcm) {
+		t.Errorf("length = %d, want %d", len(out), len(pcm))
+	}
+	if peak, _ := peakRMS(out); peak != 0 {
+		t.Errorf("peak = %v, want 0", peak)
+	}
+}
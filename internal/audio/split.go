@@ -0,0 +1,44 @@
+package audio
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// SplitAtSilence 把PCM数据切成不超过max的几段，切分点选在每段后半部分最安静的地方，
+// 尽量不把一个字切成两半。拼接所有段即为原数据
+func SplitAtSilence(f Format, pcm []byte, max time.Duration) [][]byte {
+	blockAlign := f.Channels * f.BitsPerSample / 8
+	frame := f.BytesPerSecond() / 50 / blockAlign * blockAlign // 20ms一帧
+	maxBytes := int(max.Seconds()*float64(f.BytesPerSecond())) / blockAlign * blockAlign
+	if frame == 0 || maxBytes < 2*frame {
+		return [][]byte{pcm}
+	}
+
+	var chunks [][]byte
+	for len(pcm) > maxBytes {
+		cut, quietest := maxBytes, -1.0
+		for start := maxBytes / 2 / frame * frame; start+frame <= maxBytes; start += frame {
+			if e := energy(pcm[start : start+frame]); quietest < 0 || e < quietest {
+				cut, quietest = start+frame/2/blockAlign*blockAlign, e
+			}
+		}
+		chunks = append(chunks, pcm[:cut])
+		pcm = pcm[cut:]
+	}
+	return append(chunks, pcm)
+}
+
+// energy 一段16位PCM的平均能量
+func energy(pcm []byte) float64 {
+	n := len(pcm) / 2
+	if n == 0 {
+		return 0
+	}
+	var sum float64
+	for i := 0; i < n; i++ {
+		v := float64(int16(binary.LittleEndian.Uint16(pcm[i*2:])))
+		sum += v * v
+	}
+	return sum / float64(n)
+}
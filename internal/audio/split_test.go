@@ -0,0 +1,59 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+// speech 生成16k单声道的“说话”录音：每段话是440Hz的正弦波，段与段之间有silence的静音
+func speech(talks []time.Duration, silence time.Duration) []byte {
+	var pcm []byte
+	samples := func(d time.Duration) int { return int(d.Seconds() * 16000) }
+	for i, d := range talks {
+		for j := 0; j < samples(d); j++ {
+			v := int16(8000 * math.Sin(2*math.Pi*440*float64(j)/16000))
+			pcm = binary.LittleEndian.AppendUint16(pcm, uint16(v))
+		}
+		if i < len(talks)-1 {
+			pcm = append(pcm, make([]byte, samples(silence)*2)...)
+		}
+	}
+	return pcm
+}
+
+func TestSplitAtSilence(t *testing.T) {
+	// 7秒说话、0.5秒静音、5秒说话、0.5秒静音、6秒说话，每段不超过10秒
+	pcm := speech([]time.Duration{7 * time.Second, 5 * time.Second, 6 * time.Second}, 500*time.Millisecond)
+	chunks := SplitAtSilence(Mono16k, pcm, 10*time.Second)
+
+	if len(chunks) != 3 {
+		t.Fatalf("got %d chunks, want 3", len(chunks))
+	}
+	if got := bytes.Join(chunks, nil); !bytes.Equal(got, pcm) {
+		t.Error("chunks do not join to the original data")
+	}
+
+	// 切分点应该落在静音中
+	offset := 0
+	for i, c := range chunks[:len(chunks)-1] {
+		if d := Mono16k.Duration(len(c)); d > 10*time.Second {
+			t.Errorf("chunk %d is %v, longer than 10s", i, d)
+		}
+		offset += len(c)
+		at := Mono16k.Duration(offset)
+		silences := [][2]time.Duration{{7 * time.Second, 7500 * time.Millisecond}, {12500 * time.Millisecond, 13 * time.Second}}
+		if s := silences[i]; at < s[0] || at > s[1] {
+			t.Errorf("cut %d at %v, want within %v", i, at, s)
+		}
+	}
+}
+
+func TestSplitAtSilenceShort(t *testing.T) {
+	pcm := speech([]time.Duration{time.Second}, 0)
+	if chunks := SplitAtSilence(Mono16k, pcm, 10*time.Second); len(chunks) != 1 || len(chunks[0]) != len(pcm) {
+		t.Errorf("got %d chunks", len(chunks))
+	}
+}
@@ -0,0 +1,116 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Format PCM数据的格式，只支持16位整数
+type Format struct {
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+}
+
+// 识别服务需要的格式
+var Mono16k = Format{SampleRate: 16000, Channels: 1, BitsPerSample: 16}
+
+// BytesPerSecond 每秒的数据量
+func (f Format) BytesPerSecond() int {
+	return f.SampleRate * f.Channels * f.BitsPerSample / 8
+}
+
+// ReadWAVHeader 读取WAV头，读完后r停在PCM数据的开头。
+// 录音程序输出到管道时不知道总长度，所以忽略头中的长度字段
+func ReadWAVHeader(r io.Reader) (Format, error) {
+	f, _, err := ReadWAVHeaderLen(r)
+	return f, err
+}
+
+// ReadWAVHeaderLen 和ReadWAVHeader相同，同时返回data块的长度。
+// 边生成边输出的WAV不知道总长度，头中的长度为0或0xFFFFFFFF，这时返回-1
+func ReadWAVHeaderLen(r io.Reader) (Format, int64, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return Format{}, 0, err
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return Format{}, 0, errors.New("not a wav stream")
+	}
+
+	var f Format
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return Format{}, 0, err
+		}
+		id, size := string(chunk[0:4]), binary.LittleEndian.Uint32(chunk[4:8])
+
+		switch id {
+		case "fmt ":
+			body := make([]byte, size)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return Format{}, 0, err
+			}
+			if len(body) < 16 {
+				return Format{}, 0, errors.New("short fmt chunk")
+			}
+			f.Channels = int(binary.LittleEndian.Uint16(body[2:4]))
+			f.SampleRate = int(binary.LittleEndian.Uint32(body[4:8]))
+			f.BitsPerSample = int(binary.LittleEndian.Uint16(body[14:16]))
+		case "data":
+			if f.SampleRate == 0 {
+				return Format{}, 0, errors.New("data chunk before fmt chunk")
+			}
+			if f.BitsPerSample != 16 {
+				return Format{}, 0, fmt.Errorf("unsupported bits per sample: %d", f.BitsPerSample)
+			}
+			if size == 0 || size == 0xFFFFFFFF {
+				return f, -1, nil
+			}
+			return f, int64(size), nil
+		default:
+			if _, err := io.CopyN(io.Discard, r, int64(size+size%2)); err != nil {
+				return Format{}, 0, err
+			}
+		}
+	}
+}
+
+// WAVHeader 生成16位PCM的WAV头，dataLen为PCM数据的长度
+func WAVHeader(f Format, dataLen int) []byte {
+	h := make([]byte, 44)
+	copy(h[0:], "RIFF")
+	binary.LittleEndian.PutUint32(h[4:], uint32(36+dataLen))
+	copy(h[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(h[16:], 16)
+	binary.LittleEndian.PutUint16(h[20:], 1)
+	binary.LittleEndian.PutUint16(h[22:], uint16(f.Channels))
+	binary.LittleEndian.PutUint32(h[24:], uint32(f.SampleRate))
+	binary.LittleEndian.PutUint32(h[28:], uint32(f.BytesPerSecond()))
+	binary.LittleEndian.PutUint16(h[32:], uint16(f.Channels*f.BitsPerSample/8))
+	binary.LittleEndian.PutUint16(h[34:], uint16(f.BitsPerSample))
+	copy(h[36:], "data")
+	binary.LittleEndian.PutUint32(h[40:], uint32(dataLen))
+	return h
+}
+
+// ParseWAV 解析完整的WAV数据，返回格式和其中的PCM数据
+func ParseWAV(data []byte) (Format, []byte, error) {
+	r := bytes.NewReader(data)
+	f, err := ReadWAVHeader(r)
+	if err != nil {
+		return Format{}, nil, err
+	}
+	pcm := data[len(data)-r.Len():]
+	return f, pcm[:len(pcm)/2*2], nil
+}
+
+// Duration PCM数据的时长
+func (f Format) Duration(pcmLen int) time.Duration {
+	return time.Duration(pcmLen) * time.Second / time.Duration(f.BytesPerSecond())
+}
@@ -0,0 +1,118 @@
+package persona
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"regexp"
+	"sort"
+	"unicode/utf8"
+)
+
+// 角色标签，如“孙悟空：”，出现在一行的开头
+var speakerTag = regexp.MustCompile(`^\s*([^\s:：]{1,12})\s*[:：]\s*`)
+
+// 没有指定颜色时按顺序使用的颜色
+var palette = []string{"15", "11", "14", "13", "10", "9", "12", "208"}
+
+// Voice 一个角色的声音
+type Voice struct {
+	VoiceType int64  `json:"voice_type"`
+	Emotion   string `json:"emotion"`
+	Color     string `json:"color"` // 聊天记录中的颜色，见 https://en.wikipedia.org/wiki/ANSI_escape_code#8-bit
+}
+
+// Persona 角色扮演的设定：让AI按“角色：台词”输出，每个角色用自己的声音朗读
+type Persona struct {
+	Name    string           `json:"name"`
+	Prompt  string           `json:"prompt"`  // 系统提示词，告诉AI如何标注角色
+	Default string           `json:"default"` // 没有标注角色时使用的角色，一般是旁白
+	Voices  map[string]Voice `json:"voices"`
+}
+
+// Load 从JSON文件加载所有角色扮演设定，文件不存在时返回空
+func Load(path string) ([]*Persona, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var personas []*Persona
+	if err := json.Unmarshal(data, &personas); err != nil {
+		return nil, err
+	}
+	for _, p := range personas {
+		p.assignColors()
+	}
+	return personas, nil
+}
+
+// Find 按名字查找设定
+func Find(personas []*Persona, name string) *Persona {
+	for _, p := range personas {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+func (p *Persona) assignColors() {
+	names := make([]string, 0, len(p.Voices))
+	for name := range p.Voices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	i := 0
+	for _, name := range names {
+		if v := p.Voices[name]; v.Color == "" {
+			v.Color = palette[i%len(palette)]
+			p.Voices[name] = v
+			i++
+		}
+	}
+}
+
+// Tag 识别一行开头的角色标签，返回角色名和标签的长度（按rune计）
+func Tag(line string) (string, int, bool) {
+	m := speakerTag.FindStringSubmatchIndex(line)
+	if m == nil {
+		return "", 0, false
+	}
+	return line[m[2]:m[3]], utf8.RuneCountInString(line[:m[1]]), true
+}
+
+// Speaker 和Tag一样，但只认设定中存在的角色，避免把“注意：”之类的普通文字当成角色
+func (p *Persona) Speaker(line string) (string, int, bool) {
+	if p == nil {
+		return "", 0, false
+	}
+	name, n, ok := Tag(line)
+	if _, known := p.Voices[name]; !ok || !known {
+		return "", 0, false
+	}
+	return name, n, true
+}
+
+// Voice 返回角色的声音，未知角色使用默认角色的声音
+func (p *Persona) Voice(speaker string) (Voice, bool) {
+	if v, ok := p.Voices[speaker]; ok {
+		return v, true
+	}
+	v, ok := p.Voices[p.Default]
+	return v, ok
+}
+
+// Colors 返回角色名到颜色的映射，给界面使用
+func (p *Persona) Colors() map[string]string {
+	colors := make(map[string]string, len(p.Voices))
+	for name, v := range p.Voices {
+		colors[name] = v.Color
+	}
+	return colors
+}
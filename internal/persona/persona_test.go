@@ -0,0 +1,35 @@
+package persona
+
+import "testing"
+
+func TestSpeaker(t *testing.T) {
+	p := &Persona{
+		Default: "旁白",
+		Voices: map[string]Voice{
+			"旁白":  {VoiceType: 1009},
+			"孙悟空": {VoiceType: 101016, Emotion: "exciting"},
+		},
+	}
+
+	cases := []struct {
+		line string
+		name string
+		n    int
+		ok   bool
+	}{
+		{"孙悟空：俺老孙来也！", "孙悟空", 4, true},
+		{"  旁白: 话说……", "旁白", 6, true},
+		{"注意：前方有妖怪", "", 0, false},
+		{"俺老孙来也！", "", 0, false},
+	}
+	for _, c := range cases {
+		name, n, ok := p.Speaker(c.line)
+		if name != c.name || n != c.n || ok != c.ok {
+			t.Errorf("Speaker(%q) = %q, %d, %v; want %q, %d, %v", c.line, name, n, ok, c.name, c.n, c.ok)
+		}
+	}
+
+	if v, _ := p.Voice("猪八戒"); v.VoiceType != 1009 {
+		t.Errorf("unknown speaker should use the default voice, got %d", v.VoiceType)
+	}
+}
@@ -0,0 +1,184 @@
+package myplayer
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// pipeLimit 管道中最多积压的原始音频，解码跟不上（如暂停）时写入等待
+	pipeLimit = 1 << 20
+	// jitterLimit 抖动缓冲中最多缓存的PCM时长，设备不读（如暂停）时解码等待
+	jitterLimit = 30 * time.Second
+)
+
+// pipe 收到的音频数据和解码器之间的管道：积压超过pipeLimit时写入等待解码器读走，
+// 解码器放弃后写入的数据直接丢掉；读取在没有数据时阻塞，直到有新数据或者关闭
+type pipe struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	buf       []byte
+	closed    bool
+	discarded bool
+}
+
+func newPipe() *pipe {
+	p := &pipe{}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+func (p *pipe) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(p.buf) >= pipeLimit && !p.discarded && !p.closed {
+		p.cond.Wait()
+	}
+	if p.closed {
+		return 0, io.ErrClosedPipe
+	}
+	if !p.discarded {
+		p.buf = append(p.buf, b...)
+		p.cond.Broadcast()
+	}
+	return len(b), nil
+}
+
+// discard 解码器不再读取，丢掉积压的数据，之后写入的数据也不再保存
+func (p *pipe) discard() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.buf = nil
+	p.discarded = true
+	p.cond.Broadcast()
+}
+
+// Close 数据写完了，读完剩下的数据后返回io.EOF
+func (p *pipe) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	p.cond.Broadcast()
+}
+
+func (p *pipe) Read(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(p.buf) == 0 && !p.closed {
+		p.cond.Wait()
+	}
+	if len(p.buf) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(b, p.buf)
+	p.buf = p.buf[n:]
+	p.cond.Broadcast()
+	return n, nil
+}
+
+// jitterBuffer 解码后等待播放的PCM数据，吸收网络和解码的抖动。
+// 输出设备读取时从不阻塞（见Sink）：暂时没有数据就返回0，设备先播放自己缓存的数据；
+// 解码结束并且数据都被读走后返回io.EOF。缓存超过jitterLimit时写入等待设备读走，停止后不再等待
+type jitterBuffer struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	buf       []byte
+	finished  bool
+	consumed  int64 // 输出设备已经读走（或跳过）的字节数
+	skipUntil int64 // 跳到还没解码出来的位置时，在这之前的数据到了直接丢掉
+}
+
+func newJitterBuffer() *jitterBuffer {
+	j := &jitterBuffer{}
+	j.cond = sync.NewCond(&j.mu)
+	return j
+}
+
+func (j *jitterBuffer) Write(b []byte) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	n := len(b)
+	// 停止时数据都丢掉，缓存变空，等待的写入也就结束了
+	for len(j.buf) >= bytesFor(jitterLimit) {
+		j.cond.Wait()
+	}
+	if skip := j.skipUntil - j.consumed; skip > 0 {
+		if skip > int64(len(b)) {
+			skip = int64(len(b))
+		}
+		b = b[skip:]
+		j.consumed += skip
+	}
+	j.buf = append(j.buf, b...)
+	j.cond.Broadcast()
+	return n, nil
+}
+
+// skipTo 跳到第n字节处，之前没播放的数据都丢掉
+func (j *jitterBuffer) skipTo(n int64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	skip := n - j.consumed
+	if skip <= 0 {
+		return
+	}
+	if skip > int64(len(j.buf)) {
+		skip = int64(len(j.buf))
+	}
+	j.buf = j.buf[skip:]
+	j.consumed += skip
+	j.skipUntil = n
+	j.cond.Broadcast()
+}
+
+// abort 停止播放：丢掉所有数据，之后的数据也不再接收
+func (j *jitterBuffer) abort() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.buf = nil
+	j.finished = true
+	j.skipUntil = 1<<63 - 1
+	j.cond.Broadcast()
+}
+
+// finish 解码结束，不会再有新数据
+func (j *jitterBuffer) finish() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.finished = true
+	j.cond.Broadcast()
+}
+
+func (j *jitterBuffer) Read(b []byte) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if len(j.buf) == 0 {
+		if j.finished {
+			return 0, io.EOF
+		}
+		return 0, nil
+	}
+	n := copy(b, j.buf)
+	j.buf = j.buf[n:]
+	j.consumed += int64(n)
+	j.cond.Broadcast()
+	return n, nil
+}
+
+// waitBuffered 等到缓存了至少n字节或者解码结束，返回是否有数据
+func (j *jitterBuffer) waitBuffered(n int) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for len(j.buf) < n && !j.finished {
+		j.cond.Wait()
+	}
+	return len(j.buf) > 0
+}
+
+// Consumed 输出设备已经读走的字节数
+func (j *jitterBuffer) Consumed() int64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.consumed
+}
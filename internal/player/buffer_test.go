@@ -0,0 +1,88 @@
+package myplayer
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+// writeAsync 在另一个goroutine中写入n字节，写完后关闭返回的通道
+func writeAsync(w io.Writer, n int) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		w.Write(make([]byte, n))
+		close(done)
+	}()
+	return done
+}
+
+// finished 检查done是否在超时前关闭
+func finished(done <-chan struct{}, timeout time.Duration) bool {
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func TestJitterBufferRead(t *testing.T) {
+	j := newJitterBuffer()
+	// 暂时没有数据时不阻塞，也不算结束
+	if n, err := j.Read(make([]byte, 10)); n != 0 || err != nil {
+		t.Errorf("Read on empty buffer = %d, %v; want 0, nil", n, err)
+	}
+	j.finish()
+	if _, err := j.Read(make([]byte, 10)); err != io.EOF {
+		t.Errorf("Read after finish = %v, want EOF", err)
+	}
+}
+
+func TestJitterBufferLimit(t *testing.T) {
+	j := newJitterBuffer()
+	j.Write(make([]byte, bytesFor(jitterLimit)))
+	done := writeAsync(j, 100)
+	if finished(done, 50*time.Millisecond) {
+		t.Fatal("Write did not wait when the buffer was full")
+	}
+
+	// 设备读走一些后写入继续
+	j.Read(make([]byte, 1000))
+	if !finished(done, time.Second) {
+		t.Fatal("Write still waiting after Read")
+	}
+
+	// 停止后等待的写入马上返回，数据丢掉
+	j.Write(make([]byte, 1000))
+	done = writeAsync(j, 100)
+	if finished(done, 50*time.Millisecond) {
+		t.Fatal("Write did not wait when the buffer was full")
+	}
+	j.abort()
+	if !finished(done, time.Second) {
+		t.Error("Write still waiting after abort")
+	}
+	if _, err := j.Read(make([]byte, 10)); err != io.EOF {
+		t.Errorf("Read after abort = %v, want EOF", err)
+	}
+}
+
+func TestPipeLimit(t *testing.T) {
+	p := newPipe()
+	p.Write(make([]byte, pipeLimit))
+	done := writeAsync(p, 100)
+	if finished(done, 50*time.Millisecond) {
+		t.Fatal("Write did not wait when the pipe was full")
+	}
+
+	// 解码器放弃后写入不再等待
+	p.discard()
+	if !finished(done, time.Second) {
+		t.Fatal("Write still waiting after discard")
+	}
+	p.Write(make([]byte, 100))
+	p.Close()
+	if _, err := p.Read(make([]byte, 10)); err != io.EOF {
+		t.Errorf("Read after discard = %v, want EOF", err)
+	}
+}
@@ -0,0 +1,31 @@
+package myplayer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// PlayPCM 在sink上播放一段16k双声道16位PCM（和播放器的输出格式OutputFormat相同），播放完后返回
+func PlayPCM(sink Sink, pcm []byte) {
+	player := sink.NewPlayer(bytes.NewReader(pcm))
+	defer player.Close()
+	player.Play()
+	<-player.Done()
+}
+
+// Chime 生成一声“叮咚”提示音：两个从高到低的短音，渐弱结束
+func Chime() []byte {
+	var pcm []byte
+	for _, freq := range []float64{1318.5, 987.8} { // E6, B5
+		n := 16000 * 150 / 1000
+		for i := 0; i < n; i++ {
+			t := float64(i) / 16000
+			envelope := math.Min(1, float64(i)/80) * (1 - float64(i)/float64(n))
+			v := uint16(int16(8000 * envelope * math.Sin(2*math.Pi*freq*t)))
+			pcm = binary.LittleEndian.AppendUint16(pcm, v) // 左声道
+			pcm = binary.LittleEndian.AppendUint16(pcm, v) // 右声道
+		}
+	}
+	return pcm
+}
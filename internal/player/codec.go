@@ -0,0 +1,188 @@
+package myplayer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pion/opus"
+	"github.com/pion/opus/pkg/oggreader"
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/audio"
+)
+
+// Codecs 支持的语音编码
+var Codecs = []string{"mp3", "pcm", "wav", "opus"}
+
+// DecoderFor 返回codec编码的解码器
+func DecoderFor(codec string) (Decoder, error) {
+	switch codec {
+	case "mp3":
+		return DecodeMP3, nil
+	case "pcm":
+		return DecodePCM, nil
+	case "wav":
+		return DecodeWAV, nil
+	case "opus":
+		return DecodeOpus, nil
+	}
+	return nil, fmt.Errorf("unsupported codec: %s", codec)
+}
+
+// Duration 计算一段完整的codec编码语音的时长
+func Duration(codec string, data []byte) (time.Duration, error) {
+	if codec == "mp3" {
+		return MP3Duration(data)
+	}
+	decoder, err := DecoderFor(codec)
+	if err != nil {
+		return 0, err
+	}
+	pcm, f, err := decoder(bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(io.Discard, pcm)
+	if err != nil {
+		return 0, err
+	}
+	return f.Duration(int(n)), nil
+}
+
+// DecodePCM 合成服务返回的裸PCM没有头，是16k单声道16位
+func DecodePCM(r io.Reader) (io.Reader, audio.Format, error) {
+	return r, audio.Mono16k, nil
+}
+
+// DecodeWAV 解码WAV。逐句合成时每句是一个完整的WAV，拼在一起后跳过每句的头
+func DecodeWAV(r io.Reader) (io.Reader, audio.Format, error) {
+	f, n, err := audio.ReadWAVHeaderLen(r)
+	if err != nil {
+		return nil, audio.Format{}, err
+	}
+	return &wavReader{r: r, format: f, remaining: n}, f, nil
+}
+
+// wavReader 读取一个或多个首尾相接的WAV中的PCM数据
+type wavReader struct {
+	r         io.Reader
+	format    audio.Format
+	remaining int64 // 当前WAV还没读的PCM数据，为-1时一直读到结束
+}
+
+func (w *wavReader) Read(b []byte) (int, error) {
+	for w.remaining == 0 {
+		f, n, err := audio.ReadWAVHeaderLen(w.r)
+		if err != nil {
+			return 0, err
+		}
+		if f != w.format {
+			return 0, fmt.Errorf("wav format changed from %+v to %+v", w.format, f)
+		}
+		w.remaining = n
+	}
+	if w.remaining > 0 && int64(len(b)) > w.remaining {
+		b = b[:w.remaining]
+	}
+	n, err := w.r.Read(b)
+	if w.remaining > 0 {
+		w.remaining -= int64(n)
+		if err == io.EOF && w.remaining > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+	}
+	return n, err
+}
+
+// DecodeOpus 解码Ogg封装的Opus，输出16k单声道。
+// 纯Go的解码器只支持20ms一帧的单声道SILK，这是语音编码常用的设置，其他的包会返回错误
+func DecodeOpus(r io.Reader) (io.Reader, audio.Format, error) {
+	ogg, _, err := oggreader.NewWith(r)
+	if err != nil {
+		return nil, audio.Format{}, err
+	}
+	return &opusReader{
+		ogg:        ogg,
+		decoder:    opus.NewDecoder(),
+		out:        make([]byte, 1920),
+		converters: map[opus.Bandwidth]*audio.Converter{},
+	}, audio.Mono16k, nil
+}
+
+// opusReader 逐页读取Ogg，把其中的Opus包解码为PCM
+type opusReader struct {
+	ogg        *oggreader.OggReader
+	decoder    opus.Decoder
+	packets    [][]byte // 当前页中还没解码的包
+	partial    []byte   // 还没读完的包，一个包可以跨多个段、多个页
+	pcm        []byte   // 解码出来还没被读走的数据
+	out        []byte
+	converters map[opus.Bandwidth]*audio.Converter
+}
+
+func (o *opusReader) Read(b []byte) (int, error) {
+	for len(o.pcm) == 0 {
+		if err := o.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(b, o.pcm)
+	o.pcm = o.pcm[n:]
+	return n, nil
+}
+
+// next 解码下一个包
+func (o *opusReader) next() error {
+	for len(o.packets) == 0 {
+		segments, _, err := o.ogg.ParseNextPage()
+		if errors.Is(err, io.EOF) && len(o.partial) > 0 {
+			return io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return err
+		}
+		// 长度为255的段表示包还没结束
+		for _, s := range segments {
+			o.partial = append(o.partial, s...)
+			if len(s) < 255 {
+				o.packets = append(o.packets, o.partial)
+				o.partial = nil
+			}
+		}
+	}
+	packet := o.packets[0]
+	o.packets = o.packets[1:]
+
+	switch {
+	case len(packet) == 0:
+		return nil
+	case bytes.HasPrefix(packet, []byte("OpusHead")):
+		// 逐句合成时多个Ogg首尾相接，新的一段重新开始解码
+		o.decoder = opus.NewDecoder()
+		return nil
+	case bytes.HasPrefix(packet, []byte("OpusTags")):
+		return nil
+	}
+
+	bandwidth, _, err := o.decoder.Decode(packet, o.out)
+	if err != nil {
+		return err
+	}
+	// 解码器把SILK的输出每个采样重复3次，每3个取1个还原为原来的采样率
+	samples := bandwidth.SampleRate() / 50
+	pcm := make([]byte, 0, samples*2)
+	for i := 0; i < samples; i++ {
+		pcm = append(pcm, o.out[i*6:i*6+2]...)
+	}
+	if bandwidth.SampleRate() != audio.Mono16k.SampleRate {
+		c, ok := o.converters[bandwidth]
+		if !ok {
+			c = audio.NewConverter(audio.Format{SampleRate: bandwidth.SampleRate(), Channels: 1, BitsPerSample: 16}, audio.Mono16k)
+			o.converters[bandwidth] = c
+		}
+		pcm = c.Convert(pcm)
+	}
+	o.pcm = pcm
+	return nil
+}
@@ -0,0 +1,96 @@
+package myplayer
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/audio"
+)
+
+func TestDecoderFor(t *testing.T) {
+	for _, codec := range Codecs {
+		if _, err := DecoderFor(codec); err != nil {
+			t.Errorf("DecoderFor(%q): %v", codec, err)
+		}
+	}
+	if _, err := DecoderFor("flac"); err == nil {
+		t.Error("DecoderFor(\"flac\") should fail")
+	}
+}
+
+func TestDecodeWAV(t *testing.T) {
+	// 两句话的WAV首尾相接，第二句的头不应该被当成声音
+	first, second := pcmData(3200), pcmData(1600)
+	data := append(audio.WAVHeader(audio.Mono16k, len(first)), first...)
+	data = append(data, audio.WAVHeader(audio.Mono16k, len(second))...)
+	data = append(data, second...)
+
+	pcm, f, err := DecodeWAV(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f != audio.Mono16k {
+		t.Errorf("format = %+v, want %+v", f, audio.Mono16k)
+	}
+	got, err := io.ReadAll(pcm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := append(append([]byte(nil), first...), second...); !bytes.Equal(got, want) {
+		t.Errorf("decoded %d bytes, want %d", len(got), len(want))
+	}
+
+	// 长度不对的WAV报错
+	if _, err := io.ReadAll(mustDecode(t, DecodeWAV, data[:len(data)-10])); err != io.ErrUnexpectedEOF {
+		t.Errorf("truncated wav: err = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestDecodeOpus(t *testing.T) {
+	// tiny.ogg来自pion/opus的测试数据，一个20ms的SILK包
+	data, err := os.ReadFile("testdata/tiny.ogg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(mustDecode(t, DecodeOpus, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := audio.Mono16k.Duration(len(got)); d != 20*time.Millisecond {
+		t.Errorf("decoded %v, want 20ms", d)
+	}
+
+	// 逐句合成时多个Ogg首尾相接
+	d, err := Duration("opus", append(append([]byte(nil), data...), data...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d != 40*time.Millisecond {
+		t.Errorf("Duration = %v, want 40ms", d)
+	}
+}
+
+func TestDuration(t *testing.T) {
+	d, err := Duration("pcm", make([]byte, audio.Mono16k.BytesPerSecond()/2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d != 500*time.Millisecond {
+		t.Errorf("pcm Duration = %v, want 500ms", d)
+	}
+	if _, err := Duration("flac", nil); err == nil {
+		t.Error("Duration of unknown codec should fail")
+	}
+}
+
+func mustDecode(t *testing.T, decoder Decoder, data []byte) io.Reader {
+	t.Helper()
+	pcm, _, err := decoder(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pcm
+}
@@ -2,155 +2,321 @@ package myplayer
 
 import (
 	"bytes"
+	"io"
 	"sync"
 	"time"
 
-	"github.com/ebitengine/oto/v3"
 	"github.com/hajimehoshi/go-mp3"
 	log "github.com/sirupsen/logrus"
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/audio"
 )
 
-const minDataSize = 200
+// OutputFormat 输出设备的格式，不同采样率、声道数的语音都转换为这个格式后播放
+var OutputFormat = audio.Format{SampleRate: 16000, Channels: 2, BitsPerSample: 16}
 
-var (
-	otoContext *oto.Context
-	once       sync.Once
+const (
+	// 解码出这么多数据后开始播放，之后网络偶尔慢一点也不会断断续续
+	prebuffer = 200 * time.Millisecond
+	// 输出设备自己缓存的数据，越小结束得越及时
+	sinkBuffer = 100 * time.Millisecond
 )
 
-// getOtoContext returns the singleton oto.Context
-func getOtoContext() *oto.Context {
-	once.Do(func() {
-		var err error
-		op := &oto.NewContextOptions{
-			SampleRate:   16000, // Adjust based on your MP3 sample rate
-			ChannelCount: 2,
-			Format:       oto.FormatSignedInt16LE,
-		}
+// Decoder 把收到的音频数据流解码为16位PCM，并返回PCM的格式
+type Decoder func(r io.Reader) (io.Reader, audio.Format, error)
+
+// DecodeMP3 解码MP3，采样率和MP3相同，go-mp3总是输出16位双声道
+func DecodeMP3(r io.Reader) (io.Reader, audio.Format, error) {
+	d, err := mp3.NewDecoder(r)
+	if err != nil {
+		return nil, audio.Format{}, err
+	}
+	return d, audio.Format{SampleRate: d.SampleRate(), Channels: 2, BitsPerSample: 16}, nil
+}
 
-		var readyChan chan struct{}
-		otoContext, readyChan, err = oto.NewContext(op)
+// Option 定制播放器的选项
+type Option func(*MyPlayer)
+
+// WithSink 播放到指定的输出设备，默认为NullSink不出声，扬声器见speaker包
+func WithSink(s Sink) Option {
+	return func(p *MyPlayer) {
+		p.sink = s
+	}
+}
+
+// WithDecoder 使用指定的解码器，默认为DecodeMP3，其他编码用DecoderFor取得
+func WithDecoder(d Decoder) Option {
+	return func(p *MyPlayer) {
+		p.decoder = d
+	}
+}
+
+// WithCodec 按编码选择解码器，输出设备需要保存原始音频时也按这个编码保存
+func WithCodec(codec string) Option {
+	return func(p *MyPlayer) {
+		d, err := DecoderFor(codec)
 		if err != nil {
-			log.Fatalf("oto.NewContext failed: %v", err)
+			log.Warnf("%v，按mp3解码", err)
+			return
 		}
-		<-readyChan
-	})
-	return otoContext
+		p.codec, p.decoder = codec, d
+	}
 }
 
+// MyPlayer 边收边播的播放器：收到的数据写入管道，解码后放入抖动缓冲，再由输出设备拉取播放
 type MyPlayer struct {
-	buffer       *bytes.Buffer
-	decoder      *mp3.Decoder
-	player       *oto.Player
-	audioStream  <-chan []byte
-	readFinished bool
+	audioStream <-chan []byte
+	sink        Sink
+	decoder     Decoder
+	codec       string         // 为空时不知道编码，不保存原始音频
+	record      io.WriteCloser // 输出设备要保存的原始音频
+
+	in   *pipe
+	pcm  *jitterBuffer
+	done chan struct{}
+	stop chan struct{} // Stop时关闭
+
+	mu      sync.Mutex
+	player  SinkPlayer
+	paused  bool
+	stopped bool
+	volume  float64
+	floor   time.Duration // 跳过后报告的进度至少是跳到的位置，不受设备中还没播完的数据影响
 }
 
-func NewMyPlayer(audioStream <-chan []byte) *MyPlayer {
+func NewMyPlayer(audioStream <-chan []byte, opts ...Option) *MyPlayer {
 	log.Debug("正在初始化播放器")
-	return &MyPlayer{
-		buffer:       &bytes.Buffer{},
-		audioStream:  audioStream,
-		readFinished: false,
+	p := &MyPlayer{
+		audioStream: audioStream,
+		sink:        NullSink{},
+		decoder:     DecodeMP3,
+		in:          newPipe(),
+		pcm:         newJitterBuffer(),
+		done:        make(chan struct{}),
+		stop:        make(chan struct{}),
+		volume:      1,
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
+// Play 播放audioStream中的语音，直到通道关闭并且全部播放完后返回
 func (p *MyPlayer) Play() {
-	log.Debug("正在调用播放器来播放语音")
-	go p.readFromStream()
+	defer close(p.done)
+	if rs, ok := p.sink.(RecordingSink); ok && p.codec != "" {
+		w, err := rs.Record(p.codec)
+		if err != nil {
+			log.Warnf("保存语音失败: %v", err)
+		} else {
+			p.record = w
+		}
+	}
+	go p.receive()
+	go p.decode()
 
-	for {
-		if p.readFinished || p.buffer.Len() >= minDataSize {
-			log.Debugf("已经收取足够语音数据，正在初始化解码器, readFinished:%v, buf len:%d", p.readFinished, p.buffer.Len())
-			// 确保播放器在播放前初始化
-			if p.player != nil {
-				log.Debug("播放器已经存在，关闭现有播放器")
-				p.player.Close()
-			}
+	if !p.pcm.waitBuffered(bytesFor(prebuffer)) {
+		log.Debug("没有可以播放的语音")
+		return
+	}
 
-			p.initializePlayer()
+	player := p.sink.NewPlayer(p.pcm)
+	player.SetBufferSize(bytesFor(sinkBuffer))
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		return
+	}
+	p.player = player
+	player.SetVolume(p.volume)
+	paused := p.paused
+	p.mu.Unlock()
+	if !paused {
+		player.Play()
+	}
+	log.Debug("开始播放语音")
 
-			if p.player != nil && !p.player.IsPlaying() {
-				log.Debug("未在播放中，调用播放器来播放语音, Play!")
-				time.Sleep(500 * time.Millisecond)
-				p.player.Play()
-			}
+	// 等输出设备把数据都播完；暂停时一直等到继续或者停止
+	select {
+	case <-player.Done():
+	case <-p.stop:
+	}
+	if err := player.Close(); err != nil {
+		log.Warnf("关闭播放器失败: %v", err)
+	}
+	log.Debug("语音播放完成")
+}
 
-			if p.player != nil && p.player.IsPlaying() {
-				log.Debug("播放器已经进入Playing")
-				break
-			}
+// Done 播放完成后关闭
+func (p *MyPlayer) Done() <-chan struct{} {
+	return p.done
+}
 
-			log.Debug("未在播放中，将会重置播放器!")
-		}
+// Pause 暂停播放
+func (p *MyPlayer) Pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.paused = true
+	if p.player != nil {
+		p.player.Pause()
+	}
+}
 
-		time.Sleep(100 * time.Millisecond)
+// Resume 继续播放
+func (p *MyPlayer) Resume() {
+	p.mu.Lock()
+	p.paused = false
+	player, stopped := p.player, p.stopped
+	p.mu.Unlock()
+	// 设备开始播放时会先读满自己的缓存，不要拿着锁
+	if player != nil && !stopped {
+		player.Play()
 	}
 }
 
-func (p *MyPlayer) Reset() {
-	p.buffer.Reset()
-	p.readFinished = false
+// Paused 是否暂停了
+func (p *MyPlayer) Paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
 }
 
-func (p *MyPlayer) readFromStream() {
-	for {
-		select {
-		case data, ok := <-p.audioStream:
-			if !ok {
-				// Channel is closed, stop reading
-				log.Warnf("audioStream closed? 将退出播放器")
-				p.readFinished = true
-				return
+// Stop 停止播放，剩下的语音都丢掉，Play马上返回
+func (p *MyPlayer) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.stopped {
+		p.stopped = true
+		close(p.stop)
+	}
+	if p.player != nil {
+		p.player.Pause()
+	}
+	p.pcm.abort()
+}
+
+// Stopped 是否被停止了
+func (p *MyPlayer) Stopped() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stopped
+}
+
+// SetVolume 设置音量，0~1
+func (p *MyPlayer) SetVolume(volume float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.volume = volume
+	if p.player != nil {
+		p.player.SetVolume(volume)
+	}
+}
+
+// SkipTo 跳到pos处播放，pos在当前进度之前时不动。还没收到的部分收到后直接跳过
+func (p *MyPlayer) SkipTo(pos time.Duration) {
+	if pos <= p.Position() {
+		return
+	}
+	p.mu.Lock()
+	p.floor = pos
+	p.mu.Unlock()
+	p.pcm.skipTo(int64(bytesFor(pos)))
+}
+
+// receive 把通道中的数据写入管道，通道关闭后关闭管道
+func (p *MyPlayer) receive() {
+	for data := range p.audioStream {
+		p.in.Write(data)
+		if p.record != nil {
+			if _, err := p.record.Write(data); err != nil {
+				log.Warnf("保存语音失败: %v", err)
+				p.record.Close()
+				p.record = nil
 			}
-			p.buffer.Write(data)
-			log.Debugf("收取语音数据：%d, 剩余长度:%d", len(data), p.buffer.Len())
+		}
+	}
+	p.in.Close()
+	if p.record != nil {
+		if err := p.record.Close(); err != nil {
+			log.Warnf("保存语音失败: %v", err)
 		}
 	}
 }
 
-func (p *MyPlayer) monitorPlayback() {
-	playStarted := false
+// decode 解码管道中的数据，转换为输出格式后写入抖动缓冲
+func (p *MyPlayer) decode() {
+	defer p.pcm.finish()
+	defer p.in.discard() // 解码失败时也不能让receive卡在写满的管道上
+	pcm, f, err := p.decoder(p.in)
+	if err != nil {
+		log.Warnf("初始化解码器失败: %v", err)
+		return
+	}
+	if f == OutputFormat {
+		if _, err := io.Copy(p.pcm, pcm); err != nil {
+			log.Warnf("解码语音失败: %v", err)
+		}
+		return
+	}
+
+	log.Debugf("语音格式: %+v，转换为%+v播放", f, OutputFormat)
+	c := audio.NewConverter(f, OutputFormat)
+	buf := make([]byte, 8192)
 	for {
-		if p.player != nil {
-			if p.player.IsPlaying() {
-				playStarted = true
-				log.Debug("已经进入播放状态：playStarted=true")
-			} else if playStarted && p.buffer.Len() == 0 {
-				log.Debugf("播放已经开始并结束，且语音数据已经收取完毕，将停止播放, 播放器缓存长度：%d", p.player.BufferedSize())
-				return
-			} else {
-				log.Debugf("player已初始化，但未进入播放状态，我会尝试再次调用播放，等待结束中...剩余长度:%d, 播放器缓存长度:%d, err:%v", p.buffer.Len(), p.player.BufferedSize(), p.player.Err())
-				p.player.Play()
-			}
-		} else {
-			log.Warnf("player未初始化，播放器未在播放中! 语音数据长度:%d", p.buffer.Len())
+		n, err := pcm.Read(buf)
+		if n > 0 {
+			p.pcm.Write(c.Convert(buf[:n]))
+		}
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			log.Warnf("解码语音失败: %v", err)
+			return
 		}
-		time.Sleep(time.Second)
 	}
 }
 
-func (p *MyPlayer) GracefulStop() {
-	p.monitorPlayback()
-	log.Debugf("播放正常结束")
-	if p.player != nil {
-		p.player.Close()
-		p.player = nil
+// Position 返回当前的播放进度（已经真正播出去的时长）
+func (p *MyPlayer) Position() time.Duration {
+	p.mu.Lock()
+	player, floor := p.player, p.floor
+	p.mu.Unlock()
+	if player == nil {
+		return floor
+	}
+
+	played := durationOf(p.pcm.Consumed() - int64(player.BufferedSize()))
+	if played < floor {
+		return floor
 	}
+	return played
 }
 
-func (p *MyPlayer) initializePlayer() {
-	log.Debug("正在初始化解码器")
-	var err error
-	p.decoder, err = mp3.NewDecoder(p.buffer)
+// MP3Duration 计算一段完整mp3语音的时长
+func MP3Duration(data []byte) (time.Duration, error) {
+	d, err := mp3.NewDecoder(bytes.NewReader(data))
 	if err != nil {
-		log.Fatalf("mp3.NewDecoder failed: %v", err)
-		return
+		return 0, err
 	}
+	return pcmDuration(d.Length(), d.SampleRate()), nil
+}
 
-	otoCtx := getOtoContext()
-	p.player = otoCtx.NewPlayer(p.decoder)
-	if p.player == nil {
-		log.Fatal("otoCtx.NewPlayer failed")
+// pcmDuration go-mp3解码出来的固定是16位双声道
+func pcmDuration(n int64, sampleRate int) time.Duration {
+	if sampleRate == 0 {
+		return 0
 	}
-	log.Debug("解码器初始化成功")
+	return time.Duration(n) * time.Second / time.Duration(sampleRate*4)
+}
+
+// bytesFor 输出格式下d时长的字节数
+func bytesFor(d time.Duration) int {
+	frame := OutputFormat.Channels * OutputFormat.BitsPerSample / 8
+	return int(d.Seconds()*float64(OutputFormat.SampleRate)) * frame
+}
+
+// durationOf 输出格式下n字节的时长
+func durationOf(n int64) time.Duration {
+	return OutputFormat.Duration(int(n))
 }
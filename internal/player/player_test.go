@@ -0,0 +1,437 @@
+package myplayer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/audio"
+)
+
+// fakeSink 模拟的输出设备，像oto一样从Reader拉取数据，每毫秒“播放”1/10的缓存，比实际快。
+// 每次播放（或者暂停时空转）后通知等待的测试，测试不用靠睡眠猜时间
+type fakeSink struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	played  bytes.Buffer
+	ticks   int // 播放循环转了几次
+	players int
+}
+
+func newFakeSink() *fakeSink {
+	s := &fakeSink{}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// waitPlayed 等到至少播放了n字节
+func (s *fakeSink) waitPlayed(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.played.Len() < n {
+		s.cond.Wait()
+	}
+}
+
+// waitTicks 等播放循环再转n次
+func (s *fakeSink) waitTicks(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for until := s.ticks + n; s.ticks < until; {
+		s.cond.Wait()
+	}
+}
+
+// tick 播放循环转了一次，播放了data
+func (s *fakeSink) tick(data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.played.Write(data)
+	s.ticks++
+	s.cond.Broadcast()
+}
+
+func (s *fakeSink) NewPlayer(r io.Reader) SinkPlayer {
+	s.mu.Lock()
+	s.players++
+	s.mu.Unlock()
+	return &fakePlayer{sink: s, r: r, bufferSize: bytesFor(500 * time.Millisecond), done: make(chan struct{})}
+}
+
+func (s *fakeSink) Played() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return bytes.Clone(s.played.Bytes())
+}
+
+type fakePlayer struct {
+	sink *fakeSink
+	r    io.Reader
+
+	mu         sync.Mutex
+	buf        []byte
+	bufferSize int
+	eof        bool
+	playing    bool
+	started    bool
+	closed     bool
+	volume     float64
+	done       chan struct{}
+	finished   bool
+}
+
+func (p *fakePlayer) Play() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.fill()
+	p.playing = !p.eof || len(p.buf) > 0
+	if !p.playing {
+		p.finish()
+	}
+	if !p.started {
+		p.started = true
+		go p.loop()
+	}
+}
+
+func (p *fakePlayer) Pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.playing = false
+}
+
+// fill 从Reader读取数据，直到缓存满了或者暂时没有数据
+func (p *fakePlayer) fill() {
+	b := make([]byte, 1024)
+	for !p.eof && len(p.buf) < p.bufferSize {
+		n, err := p.r.Read(b)
+		p.buf = append(p.buf, b[:n]...)
+		if err == io.EOF {
+			p.eof = true
+		}
+		if n == 0 {
+			return
+		}
+	}
+}
+
+func (p *fakePlayer) loop() {
+	for {
+		time.Sleep(time.Millisecond)
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return
+		}
+		if !p.playing {
+			p.mu.Unlock()
+			p.sink.tick(nil)
+			continue
+		}
+		p.fill()
+		n := p.bufferSize / 10
+		if n > len(p.buf) {
+			n = len(p.buf)
+		}
+		p.sink.tick(p.buf[:n])
+		p.buf = p.buf[n:]
+		if p.eof && len(p.buf) == 0 {
+			p.playing = false
+			p.finish()
+		}
+		p.mu.Unlock()
+	}
+}
+
+// finish 数据都播完了，调用时持有锁
+func (p *fakePlayer) finish() {
+	if !p.finished {
+		p.finished = true
+		close(p.done)
+	}
+}
+
+func (p *fakePlayer) Done() <-chan struct{} {
+	return p.done
+}
+
+func (p *fakePlayer) SetVolume(v float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.volume = v
+}
+
+func (p *fakePlayer) IsPlaying() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.playing
+}
+
+func (p *fakePlayer) BufferedSize() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.buf)
+}
+
+func (p *fakePlayer) SetBufferSize(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.bufferSize = n
+}
+
+func (p *fakePlayer) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	return nil
+}
+
+// rawPCM 不解码，收到的就是输出格式的PCM
+func rawPCM(r io.Reader) (io.Reader, audio.Format, error) { return r, OutputFormat, nil }
+
+// pcmData 生成n字节可以区分位置的数据
+func pcmData(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	return data
+}
+
+func TestPlayStream(t *testing.T) {
+	sink := newFakeSink()
+	stream := make(chan []byte, 4)
+	p := NewMyPlayer(stream, WithSink(sink), WithDecoder(rawPCM))
+
+	// 1.5秒的语音，大小不一地分块发送，中间停顿一下，让缓冲暂时见底
+	data := pcmData(bytesFor(1500 * time.Millisecond))
+	go func() {
+		rest := data
+		for i := 0; len(rest) > 0; i++ {
+			n := 1000 + i*37%3000
+			if n > len(rest) {
+				n = len(rest)
+			}
+			stream <- rest[:n]
+			rest = rest[n:]
+			if i == 20 {
+				time.Sleep(100 * time.Millisecond)
+			}
+		}
+		close(stream)
+	}()
+
+	// 播放时查询进度，进度只增不减
+	var last time.Duration
+	positions := make(chan error)
+	go func() {
+		for {
+			select {
+			case <-p.Done():
+				positions <- nil
+				return
+			default:
+			}
+			pos := p.Position()
+			if pos < last {
+				positions <- errors.New("position went backwards")
+				return
+			}
+			last = pos
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	p.Play()
+	select {
+	case <-p.Done():
+	default:
+		t.Error("Done is not closed after Play returned")
+	}
+	if err := <-positions; err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(sink.Played(), data) {
+		t.Errorf("played %d bytes, want the %d bytes sent in order", len(sink.Played()), len(data))
+	}
+	if pos := p.Position(); pos != 1500*time.Millisecond {
+		t.Errorf("Position() after playing = %v, want 1.5s", pos)
+	}
+}
+
+func TestPlayShort(t *testing.T) {
+	// 比预先缓冲还短的语音，通道关闭后也要播放
+	sink := newFakeSink()
+	stream := make(chan []byte, 1)
+	data := pcmData(1000)
+	stream <- data
+	close(stream)
+
+	p := NewMyPlayer(stream, WithSink(sink), WithDecoder(rawPCM))
+	p.Play()
+	if !bytes.Equal(sink.Played(), data) {
+		t.Errorf("played %d bytes, want %d", len(sink.Played()), len(data))
+	}
+}
+
+func TestPlayEmpty(t *testing.T) {
+	sink := newFakeSink()
+	stream := make(chan []byte)
+	close(stream)
+
+	NewMyPlayer(stream, WithSink(sink), WithDecoder(rawPCM)).Play()
+	if sink.players != 0 {
+		t.Errorf("created %d players for an empty stream", sink.players)
+	}
+}
+
+func TestPlayDecodeError(t *testing.T) {
+	// 解码失败时不会卡住，发送方也不会被阻塞
+	sink := newFakeSink()
+	stream := make(chan []byte)
+	p := NewMyPlayer(stream, WithSink(sink), WithDecoder(func(io.Reader) (io.Reader, audio.Format, error) {
+		return nil, audio.Format{}, errors.New("bad data")
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			stream <- pcmData(100)
+		}
+		close(stream)
+		close(done)
+	}()
+	p.Play()
+	<-done
+	if sink.players != 0 {
+		t.Errorf("created %d players after a decode error", sink.players)
+	}
+}
+
+func TestPlayResample(t *testing.T) {
+	// 8k单声道的440Hz正弦波，播放时应转换为输出格式，音调不变
+	f := audio.Format{SampleRate: 8000, Channels: 1, BitsPerSample: 16}
+	var data []byte
+	for i := 0; i < 8000; i++ {
+		v := int16(10000 * math.Sin(2*math.Pi*440*float64(i)/8000))
+		data = binary.LittleEndian.AppendUint16(data, uint16(v))
+	}
+
+	sink := newFakeSink()
+	stream := make(chan []byte, 1)
+	stream <- data
+	close(stream)
+	p := NewMyPlayer(stream, WithSink(sink), WithDecoder(func(r io.Reader) (io.Reader, audio.Format, error) {
+		return r, f, nil
+	}))
+	p.Play()
+
+	played := sink.Played()
+	if d := OutputFormat.Duration(len(played)); d < 990*time.Millisecond || d > time.Second {
+		t.Errorf("played %v, want about 1s", d)
+	}
+	if pos := p.Position(); pos != OutputFormat.Duration(len(played)) {
+		t.Errorf("Position() = %v, want %v", pos, OutputFormat.Duration(len(played)))
+	}
+
+	// 数左声道的过零次数，440Hz一秒大约880次
+	frame := OutputFormat.Channels * 2
+	crossings := 0
+	prev := int16(0)
+	for i := 0; i+frame <= len(played); i += frame {
+		v := int16(binary.LittleEndian.Uint16(played[i:]))
+		if i > 0 && (v >= 0) != (prev >= 0) {
+			crossings++
+		}
+		prev = v
+	}
+	if crossings < 870 || crossings > 890 {
+		t.Errorf("got %d zero crossings, want about 880", crossings)
+	}
+}
+
+// playAsync 在后台播放data，返回播放器
+func playAsync(sink *fakeSink, data []byte) *MyPlayer {
+	stream := make(chan []byte, 1)
+	stream <- data
+	close(stream)
+	p := NewMyPlayer(stream, WithSink(sink), WithDecoder(rawPCM))
+	go p.Play()
+	return p
+}
+
+func TestPauseResume(t *testing.T) {
+	sink := newFakeSink()
+	data := pcmData(bytesFor(time.Second))
+	p := playAsync(sink, data)
+
+	sink.waitPlayed(1)
+	p.Pause()
+	paused := len(sink.Played())
+	sink.waitTicks(10)
+	if n := len(sink.Played()); n != paused {
+		t.Errorf("played %d more bytes while paused", n-paused)
+	}
+	select {
+	case <-p.Done():
+		t.Fatal("playback finished while paused")
+	default:
+	}
+
+	p.Resume()
+	<-p.Done()
+	if !bytes.Equal(sink.Played(), data) {
+		t.Errorf("played %d bytes, want the %d bytes in order", len(sink.Played()), len(data))
+	}
+}
+
+func TestStop(t *testing.T) {
+	sink := newFakeSink()
+	data := pcmData(bytesFor(10 * time.Second))
+	p := playAsync(sink, data)
+
+	sink.waitPlayed(1)
+	p.Stop()
+	select {
+	case <-p.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Play did not return after Stop")
+	}
+	played := sink.Played()
+	if len(played) == 0 || len(played) >= len(data) || !bytes.Equal(played, data[:len(played)]) {
+		t.Errorf("played %d of %d bytes, want a prefix", len(played), len(data))
+	}
+}
+
+func TestSkipTo(t *testing.T) {
+	sink := newFakeSink()
+	stream := make(chan []byte, 2)
+	data := pcmData(bytesFor(2 * time.Second))
+	p := NewMyPlayer(stream, WithSink(sink), WithDecoder(rawPCM))
+
+	// 跳到还没收到的位置，收到后前面的部分直接丢掉
+	p.SkipTo(1500 * time.Millisecond)
+	if pos := p.Position(); pos != 1500*time.Millisecond {
+		t.Errorf("Position() after SkipTo = %v, want 1.5s", pos)
+	}
+	stream <- data[:len(data)/2]
+	stream <- data[len(data)/2:]
+	close(stream)
+	p.Play()
+
+	skip := bytesFor(1500 * time.Millisecond)
+	if !bytes.Equal(sink.Played(), data[skip:]) {
+		t.Errorf("played %d bytes, want the last %d", len(sink.Played()), len(data)-skip)
+	}
+	if pos := p.Position(); pos != 2*time.Second {
+		t.Errorf("Position() = %v, want 2s", pos)
+	}
+}
@@ -0,0 +1,233 @@
+package myplayer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Sink 音频输出设备，从r中拉取输出格式的PCM数据播放。
+// r.Read从不阻塞：网络或解码暂时跟不上时返回(0, nil)，设备应继续播放自己缓存的数据，
+// 稍后再读，不要当作结束；数据全部读完后才返回io.EOF
+type Sink interface {
+	NewPlayer(r io.Reader) SinkPlayer
+}
+
+// SinkPlayer 输出设备上的一路播放，speaker包把*oto.Player包装为这个接口
+type SinkPlayer interface {
+	Play()
+	Pause()
+	IsPlaying() bool
+	SetVolume(volume float64)
+	// BufferedSize 已经从Reader读走、还没有播放出去的字节数
+	BufferedSize() int
+	SetBufferSize(bufferSize int)
+	// Done Reader返回io.EOF并且缓存的数据都播出去后关闭
+	Done() <-chan struct{}
+	Close() error
+}
+
+// RecordingSink 除了播放解码后的PCM，还要保存收到的原始音频的输出设备
+type RecordingSink interface {
+	Sink
+	// Record 返回保存一轮codec编码的原始音频的Writer，播放器收完数据后关闭它
+	Record(codec string) (io.WriteCloser, error)
+}
+
+// NullSink 不输出声音，只按实际时长消费数据，播放进度和控制都和扬声器一样
+type NullSink struct{}
+
+func (NullSink) NewPlayer(r io.Reader) SinkPlayer {
+	return newClockPlayer(r, io.Discard)
+}
+
+// WriterSink 按实际时长把输出格式的PCM写入W，如标准输出，可以用管道交给其他程序播放
+type WriterSink struct {
+	W io.Writer
+}
+
+func (s WriterSink) NewPlayer(r io.Reader) SinkPlayer {
+	return newClockPlayer(r, s.W)
+}
+
+// FileSink 不输出声音，每轮收到的原始音频写入Dir下的一个文件，
+// 逐句合成时文件中是首尾相接的一段段MP3或WAV。播放像NullSink一样按实际时长进行
+type FileSink struct {
+	NullSink
+	Dir string
+}
+
+// Record 在Dir下创建以时间命名、codec为扩展名的文件，如20240601-123000.mp3，重名时加上序号
+func (s FileSink) Record(codec string) (io.WriteCloser, error) {
+	name := time.Now().Format("20060102-150405")
+	for i := 1; ; i++ {
+		path := filepath.Join(s.Dir, name+"."+codec)
+		if i > 1 {
+			path = filepath.Join(s.Dir, fmt.Sprintf("%s-%d.%s", name, i, codec))
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		log.Debugf("语音写入文件: %s", path)
+		return f, nil
+	}
+}
+
+// clockPlayer 没有声卡时的播放：像oto一样先读满自己的缓存，再按实际时长把数据交给w
+type clockPlayer struct {
+	r io.Reader
+	w io.Writer
+
+	mu         sync.Mutex
+	buf        []byte // 已经读走、还没有播放的数据
+	bufferSize int
+	playing    bool
+	eof        bool
+	closed     bool
+	played     int64
+	start      time.Time
+	done       chan struct{}
+	finished   bool // done是否已经关闭
+}
+
+// clockTick 多久播放一次
+const clockTick = 10 * time.Millisecond
+
+func newClockPlayer(r io.Reader, w io.Writer) *clockPlayer {
+	p := &clockPlayer{r: r, w: w, bufferSize: bytesFor(sinkBuffer), start: time.Now(), done: make(chan struct{})}
+	go p.loop()
+	return p
+}
+
+func (p *clockPlayer) loop() {
+	t := time.NewTicker(clockTick)
+	defer t.Stop()
+	last := time.Now()
+	var owed time.Duration // 应该播放、还没有播放的时长
+	for now := range t.C {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return
+		}
+		if !p.playing {
+			owed = 0
+			last = now
+			p.mu.Unlock()
+			continue
+		}
+
+		owed += now.Sub(last)
+		last = now
+		p.fill()
+		n := bytesFor(owed)
+		if n >= len(p.buf) {
+			// 数据不够时不补静音，欠下的时长也不再追
+			n = len(p.buf)
+			owed = 0
+		} else {
+			owed -= durationOf(int64(n))
+		}
+		data := p.buf[:n]
+		p.buf = p.buf[n:]
+		p.played += int64(n)
+		drained := p.eof && len(p.buf) == 0
+		if drained {
+			p.playing = false
+		}
+		p.mu.Unlock()
+
+		if _, err := p.w.Write(data); err != nil {
+			log.Warnf("输出语音失败: %v", err)
+		}
+		if drained {
+			p.finish()
+		}
+	}
+}
+
+// finish 数据都播完了，通知Done
+func (p *clockPlayer) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.finished {
+		p.finished = true
+		close(p.done)
+	}
+}
+
+func (p *clockPlayer) Done() <-chan struct{} {
+	return p.done
+}
+
+// fill 从r读满缓存，暂时没有数据时不等待
+func (p *clockPlayer) fill() {
+	for !p.eof && len(p.buf) < p.bufferSize {
+		b := make([]byte, p.bufferSize-len(p.buf))
+		n, err := p.r.Read(b)
+		p.buf = append(p.buf, b[:n]...)
+		if err != nil {
+			p.eof = true
+		}
+		if n == 0 {
+			return
+		}
+	}
+}
+
+func (p *clockPlayer) Play() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fill()
+	p.playing = len(p.buf) > 0 || !p.eof
+	if !p.playing && !p.finished {
+		p.finished = true
+		close(p.done)
+	}
+}
+
+func (p *clockPlayer) Pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.playing = false
+}
+
+func (p *clockPlayer) IsPlaying() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.playing
+}
+
+// SetVolume 输出的是原始数据，不调节音量
+func (p *clockPlayer) SetVolume(volume float64) {}
+
+func (p *clockPlayer) BufferedSize() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.buf)
+}
+
+func (p *clockPlayer) SetBufferSize(bufferSize int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.bufferSize = bufferSize
+}
+
+func (p *clockPlayer) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.closed {
+		p.closed = true
+		log.Debugf("输出了%v的语音，用时%v", durationOf(p.played), time.Since(p.start).Round(time.Millisecond))
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package myplayer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer 可以在播放的同时读取的Buffer
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf.Bytes())
+}
+
+func TestWriterSink(t *testing.T) {
+	// 没有声卡时按实际时长输出，数据原样写出
+	data := pcmData(bytesFor(300 * time.Millisecond))
+	out := &syncBuffer{}
+	stream := make(chan []byte, 1)
+	stream <- data
+	close(stream)
+
+	start := time.Now()
+	p := NewMyPlayer(stream, WithSink(WriterSink{W: out}), WithDecoder(rawPCM))
+	p.Play()
+	elapsed := time.Since(start)
+
+	if elapsed < 250*time.Millisecond || elapsed > time.Second {
+		t.Errorf("played 300ms in %v", elapsed)
+	}
+	if got := out.Bytes(); !bytes.Equal(got, data) {
+		t.Errorf("wrote %d bytes, want %d", len(got), len(data))
+	}
+	if pos := p.Position(); pos != 300*time.Millisecond {
+		t.Errorf("Position() = %v, want 300ms", pos)
+	}
+}
+
+func TestNullSinkPause(t *testing.T) {
+	stream := make(chan []byte, 1)
+	stream <- pcmData(bytesFor(time.Second))
+	close(stream)
+	p := NewMyPlayer(stream, WithSink(NullSink{}), WithDecoder(rawPCM))
+	go p.Play()
+
+	time.Sleep(300 * time.Millisecond)
+	p.Pause()
+	time.Sleep(50 * time.Millisecond)
+	pos := p.Position()
+	time.Sleep(200 * time.Millisecond)
+	if got := p.Position(); got != pos {
+		t.Errorf("position moved from %v to %v while paused", pos, got)
+	}
+	p.Stop()
+	<-p.Done()
+}
+
+func TestFileSink(t *testing.T) {
+	// 每轮收到的原始音频原样写入一个文件，扩展名为编码
+	dir := t.TempDir()
+	first, second := pcmData(3200), pcmData(1600)
+	stream := make(chan []byte, 2)
+	stream <- first
+	stream <- second
+	close(stream)
+	NewMyPlayer(stream, WithSink(FileSink{Dir: dir}), WithCodec("pcm")).Play()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.pcm"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("files = %v, %v, want one pcm file", files, err)
+	}
+	got, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := append(append([]byte(nil), first...), second...); !bytes.Equal(got, want) {
+		t.Errorf("file has %d bytes, want %d", len(got), len(want))
+	}
+}
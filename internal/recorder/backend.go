@@ -0,0 +1,243 @@
+package recorder
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/audio"
+)
+
+// Backend 录音后端，负责从麦克风（或文件）读取16位PCM数据
+type Backend interface {
+	Name() string
+	// Available 当前机器上能否使用，比如录音程序是否已安装
+	Available() bool
+	// Open 开始录音，want为希望的格式。返回PCM数据流和实际的格式，关闭数据流即结束录音
+	Open(want audio.Format) (io.ReadCloser, audio.Format, error)
+}
+
+// commandBackend 调用外部录音程序，程序把录音写到标准输出。
+// 能输出WAV的程序输出WAV，实际格式以WAV头为准，设备不支持要求的格式时也不会弄错；
+// parecord和pw-record只能输出原始PCM，由音频服务转换为要求的格式
+type commandBackend struct {
+	name string
+	bin  string
+	args func(f audio.Format) []string
+	wav  bool // 程序输出带WAV头的数据
+}
+
+func (b commandBackend) Name() string { return b.name }
+
+func (b commandBackend) Available() bool {
+	_, err := exec.LookPath(b.bin)
+	return err == nil
+}
+
+func (b commandBackend) Open(want audio.Format) (io.ReadCloser, audio.Format, error) {
+	cmd := exec.Command(b.bin, b.args(want)...)
+	// 通过io.Pipe转发输出，Wait会等数据都转发完，结束录音时不会丢掉最后一段
+	pr, pw := io.Pipe()
+	var stderr bytes.Buffer
+	cmd.Stdout = pw
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, audio.Format{}, fmt.Errorf("%s: %w", b.name, err)
+	}
+	c := &commandReader{PipeReader: pr, cmd: cmd, stderr: &stderr, exited: make(chan struct{})}
+	go func() {
+		c.err = cmd.Wait()
+		pw.Close()
+		close(c.exited)
+	}()
+	if !b.wav {
+		return c, want, nil
+	}
+
+	// 程序打不开设备时会直接退出，读WAV头就会出错
+	f, err := audio.ReadWAVHeader(c)
+	if err != nil {
+		c.Close()
+		return nil, audio.Format{}, fmt.Errorf("%s: 读取WAV头失败: %w: %s", b.name, err, strings.TrimSpace(stderr.String()))
+	}
+	return c, f, nil
+}
+
+// commandReader 关闭时中断录音程序，等它退出后结束数据流。
+// 关闭时要有人在读数据，否则录音程序最后的输出写不出去
+type commandReader struct {
+	*io.PipeReader
+	cmd    *exec.Cmd
+	stderr *bytes.Buffer
+	exited chan struct{} // 程序退出、输出都转发完后关闭
+	err    error         // 程序退出的结果，exited关闭后有效
+}
+
+func (c *commandReader) Close() error {
+	if err := c.cmd.Process.Signal(os.Interrupt); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		c.cmd.Process.Kill()
+	}
+	<-c.exited
+	err := c.err
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && !exitErr.Exited() {
+		// 被信号中断是正常的结束方式
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(c.stderr.String()))
+	}
+	return nil
+}
+
+// 录音程序的参数：都输出有符号16位小端的PCM，sox、arecord和ffmpeg带WAV头
+var (
+	Sox = commandBackend{name: "sox", bin: "sox", wav: true, args: func(f audio.Format) []string {
+		return []string{"-q", "-d", "-t", "wav", "-r", itoa(f.SampleRate), "-c", itoa(f.Channels), "-b", "16", "-e", "signed-integer", "-"}
+	}}
+	Arecord = commandBackend{name: "arecord", bin: "arecord", wav: true, args: func(f audio.Format) []string {
+		return []string{"-q", "-t", "wav", "-f", "S16_LE", "-r", itoa(f.SampleRate), "-c", itoa(f.Channels)}
+	}}
+	Parecord = commandBackend{name: "parecord", bin: "parecord", args: func(f audio.Format) []string {
+		return []string{"--raw", "--format=s16le", "--rate=" + itoa(f.SampleRate), "--channels=" + itoa(f.Channels)}
+	}}
+	PwRecord = commandBackend{name: "pw-record", bin: "pw-record", args: func(f audio.Format) []string {
+		return []string{"--format", "s16", "--rate", itoa(f.SampleRate), "--channels", itoa(f.Channels), "-"}
+	}}
+	Ffmpeg = commandBackend{name: "ffmpeg", bin: "ffmpeg", wav: true, args: func(f audio.Format) []string {
+		// 各系统的采集设备不同
+		input := []string{"-f", "pulse", "-i", "default"}
+		switch runtime.GOOS {
+		case "darwin":
+			input = []string{"-f", "avfoundation", "-i", ":0"}
+		case "windows":
+			input = []string{"-f", "dshow", "-i", "audio=default"}
+		}
+		args := append([]string{"-loglevel", "error", "-nostdin"}, input...)
+		return append(args, "-f", "wav", "-acodec", "pcm_s16le", "-ar", itoa(f.SampleRate), "-ac", itoa(f.Channels), "-")
+	}}
+)
+
+// Backends 自动选择时依次尝试的录音后端
+var Backends = []Backend{Sox, PwRecord, Parecord, Arecord, Ffmpeg}
+
+func itoa(i int) string { return strconv.Itoa(i) }
+
+// FileBackend 从WAV、MP3文件或标准输入（Path为"-"，WAV格式）读取录音，
+// 按实际时长的速度输出，用于测试和没有麦克风的机器
+type FileBackend struct {
+	Path string
+}
+
+func (b FileBackend) Name() string { return "file:" + b.Path }
+
+func (b FileBackend) Available() bool {
+	if b.Path == "-" {
+		return true
+	}
+	_, err := os.Stat(b.Path)
+	return err == nil
+}
+
+func (b FileBackend) Open(audio.Format) (io.ReadCloser, audio.Format, error) {
+	var wav []byte
+	var err error
+	if b.Path == "-" {
+		wav, err = io.ReadAll(os.Stdin)
+		if err == nil {
+			wav, err = audio.Decode(wav)
+		}
+	} else {
+		wav, err = audio.LoadFile(b.Path)
+	}
+	if err != nil {
+		return nil, audio.Format{}, err
+	}
+	f, pcm, err := audio.ParseWAV(wav)
+	if err != nil {
+		return nil, audio.Format{}, err
+	}
+	return newPacedReader(pcm, f), f, nil
+}
+
+// pacedReader 按录音的实际速度输出数据，像真的在录音一样
+type pacedReader struct {
+	data   []byte
+	format audio.Format
+	start  time.Time
+	sent   int
+
+	once   sync.Once
+	closed chan struct{}
+}
+
+func newPacedReader(data []byte, f audio.Format) *pacedReader {
+	return &pacedReader{data: data, format: f, start: time.Now(), closed: make(chan struct{})}
+}
+
+func (p *pacedReader) Read(b []byte) (int, error) {
+	if p.sent >= len(p.data) {
+		return 0, io.EOF
+	}
+	// 每次最多输出20ms的数据，并等到这些数据“录完”
+	chunk := p.format.BytesPerSecond() / 50
+	chunk -= chunk % (p.format.Channels * 2)
+	n := len(p.data) - p.sent
+	if n > chunk {
+		n = chunk
+	}
+	if n > len(b) {
+		n = len(b)
+	}
+	due := p.start.Add(p.format.Duration(p.sent + n))
+	select {
+	case <-time.After(time.Until(due)):
+	case <-p.closed:
+		return 0, io.EOF
+	}
+	copy(b, p.data[p.sent:p.sent+n])
+	p.sent += n
+	return n, nil
+}
+
+func (p *pacedReader) Close() error {
+	p.once.Do(func() { close(p.closed) })
+	return nil
+}
+
+// Detect 选择录音后端。name为空时自动选择第一个可用的后端，
+// name为"file:路径"时从文件读取录音
+func Detect(name string) (Backend, error) {
+	if path, ok := strings.CutPrefix(name, "file:"); ok {
+		b := FileBackend{Path: path}
+		if !b.Available() {
+			return nil, fmt.Errorf("录音文件不存在: %s", path)
+		}
+		return b, nil
+	}
+
+	for _, b := range Backends {
+		if name != "" && b.Name() != name {
+			continue
+		}
+		if b.Available() {
+			return b, nil
+		}
+		if name != "" {
+			return nil, fmt.Errorf("录音后端%s不可用，请先安装", name)
+		}
+	}
+	if name != "" {
+		return nil, fmt.Errorf("未知的录音后端: %s", name)
+	}
+	return nil, errors.New("没有找到可用的录音程序，请安装sox、pipewire、pulseaudio、alsa-utils或ffmpeg中的一个")
+}
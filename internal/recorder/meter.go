@@ -0,0 +1,74 @@
+package recorder
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/audio"
+)
+
+// Level 录音的音量，RMS和Peak归一化到0~1
+type Level struct {
+	RMS     float64
+	Peak    float64
+	Elapsed time.Duration // 已经录了多久
+	Silence time.Duration // 已经连续多久没有声音
+}
+
+// 每levelWindow计算一次音量
+const levelWindow = 100 * time.Millisecond
+
+// 峰值低于silentPeak时认为没有声音
+const silentPeak = 0.02
+
+// meter 统计写入的PCM数据的音量，每个窗口发送一次，没人接收时丢弃
+type meter struct {
+	format audio.Format
+	window int
+	buf    []byte
+	total  int
+	level  Level
+	out    chan<- Level
+}
+
+func newMeter(f audio.Format, out chan<- Level) *meter {
+	blockAlign := f.Channels * f.BitsPerSample / 8
+	window := int(levelWindow.Seconds()*float64(f.BytesPerSecond())) / blockAlign * blockAlign
+	return &meter{format: f, window: window, out: out}
+}
+
+func (m *meter) Write(p []byte) (int, error) {
+	m.buf = append(m.buf, p...)
+	for len(m.buf) >= m.window {
+		m.measure(m.buf[:m.window])
+		m.buf = m.buf[m.window:]
+	}
+	m.buf = append([]byte(nil), m.buf...)
+	return len(p), nil
+}
+
+func (m *meter) measure(pcm []byte) {
+	var sum, peak float64
+	n := len(pcm) / 2
+	for i := 0; i < n; i++ {
+		v := math.Abs(float64(int16(binary.LittleEndian.Uint16(pcm[i*2:])))) / 32768
+		sum += v * v
+		peak = math.Max(peak, v)
+	}
+
+	m.total += len(pcm)
+	m.level.RMS = math.Sqrt(sum / float64(n))
+	m.level.Peak = peak
+	m.level.Elapsed = m.format.Duration(m.total)
+	if peak < silentPeak {
+		m.level.Silence += m.format.Duration(len(pcm))
+	} else {
+		m.level.Silence = 0
+	}
+
+	select {
+	case m.out <- m.level:
+	default:
+	}
+}
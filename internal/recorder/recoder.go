@@ -2,43 +2,110 @@ package recorder
 
 import (
 	"bytes"
-	"os"
-	"os/exec"
+	"encoding/binary"
+	"errors"
+	"io"
 
 	log "github.com/sirupsen/logrus"
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/audio"
 )
 
 type Recorder struct {
-	buf bytes.Buffer
-	cmd *exec.Cmd
+	backend Backend
+	format  audio.Format // 本次录音的实际格式
+
+	buf    bytes.Buffer
+	source io.ReadCloser
+	done   chan struct{} // 录音数据读完后关闭
+	stream chan []byte
+	levels chan Level
 }
 
-func NewRecorder() *Recorder {
-	return &Recorder{}
+// NewRecorder 使用backend录音，录音格式尽量为16k单声道，省去识别前的转换。
+// backend为nil时开始录音会返回错误
+func NewRecorder(backend Backend) *Recorder {
+	return &Recorder{backend: backend, levels: make(chan Level, 1)}
 }
 
-func (r *Recorder) Start() {
-	r.buf.Reset()
-	r.cmd = exec.Command("sox", "-d", "-t", "wav", "-")
-	r.cmd.Stdout = &r.buf
+// Levels 录音时每100ms报告一次音量，没有及时取走的会被丢弃
+func (r *Recorder) Levels() <-chan Level {
+	return r.levels
+}
+
+// Backend 使用的录音后端
+func (r *Recorder) Backend() Backend {
+	return r.backend
+}
+
+// Format 本次录音的格式，开始录音后才有效
+func (r *Recorder) Format() audio.Format {
+	return r.format
+}
 
-	err := r.cmd.Start()
+func (r *Recorder) Start() error {
+	return r.start(&r.buf)
+}
+
+// StartStream 开始录音，并返回录音数据流（WAV格式，和Buffer中的内容相同），录音结束后关闭
+func (r *Recorder) StartStream() (<-chan []byte, error) {
+	stream := make(chan []byte, 256)
+	if err := r.start(io.MultiWriter(&r.buf, chanWriter(stream))); err != nil {
+		return nil, err
+	}
+	r.stream = stream
+	return stream, nil
+}
+
+func (r *Recorder) start(w io.Writer) error {
+	r.buf.Reset()
+	if r.backend == nil {
+		return errors.New("没有可用的录音程序")
+	}
+	source, f, err := r.backend.Open(audio.Mono16k)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	r.source, r.format = source, f
+	log.Debugf("开始录音，后端: %s，格式: %+v", r.backend.Name(), f)
+
+	// 不知道会录多长，先写一个长度为0的WAV头，结束后再补上
+	w.Write(audio.WAVHeader(f, 0))
+	r.done = make(chan struct{})
+	go func() {
+		defer close(r.done)
+		if _, err := io.Copy(io.MultiWriter(w, newMeter(f, r.levels)), source); err != nil {
+			log.Warnf("读取录音数据失败: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Recording 是否正在录音
+func (r *Recorder) Recording() bool {
+	return r.source != nil
 }
 
 // Stop recording
 func (r *Recorder) Stop() {
-	err := r.cmd.Process.Signal(os.Interrupt)
-	if err != nil {
-		log.Fatal(err)
+	if r.source == nil {
+		return
+	}
+	if err := r.source.Close(); err != nil {
+		log.Warnf("结束录音失败: %v", err)
 	}
+	<-r.done
+	r.source = nil
 
-	// Wait for the recording process to finish
-	err = r.cmd.Wait()
-	if err != nil {
-		log.Fatal(err)
+	// 补上WAV头中的长度
+	data := r.buf.Bytes()
+	if len(data) >= 44 {
+		binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+		binary.LittleEndian.PutUint32(data[40:], uint32(len(data)-44))
+	}
+
+	if r.stream != nil {
+		close(r.stream)
+		r.stream = nil
 	}
 
 	log.Debugf("Recording stopped. recorded %d bytes", r.buf.Len())
@@ -47,3 +114,11 @@ func (r *Recorder) Stop() {
 func (r *Recorder) Buffer() *bytes.Buffer {
 	return &r.buf
 }
+
+// chanWriter 把写入的数据复制一份发送到通道
+type chanWriter chan []byte
+
+func (c chanWriter) Write(p []byte) (int, error) {
+	c <- append([]byte(nil), p...)
+	return len(p), nil
+}
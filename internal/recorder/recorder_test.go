@@ -0,0 +1,169 @@
+package recorder
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/audio"
+)
+
+func TestFileBackend(t *testing.T) {
+	pcm := bytes.Repeat([]byte{1, 0}, 16000/5) // 200ms
+	path := filepath.Join(t.TempDir(), "question.wav")
+	if err := os.WriteFile(path, append(audio.WAVHeader(audio.Mono16k, len(pcm)), pcm...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := Detect("file:" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRecorder(b)
+	stream, err := r.StartStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 按实际速度输出，不会一下子读完
+	start := time.Now()
+	var streamed []byte
+	for data := range streamAfter(stream, 300*time.Millisecond, r) {
+		streamed = append(streamed, data...)
+	}
+	if d := time.Since(start); d < 150*time.Millisecond {
+		t.Errorf("file was read in %v, want about 200ms", d)
+	}
+
+	if r.Format() != audio.Mono16k {
+		t.Errorf("format = %+v", r.Format())
+	}
+	wav := r.Buffer().Bytes()
+	f, got, err := audio.ParseWAV(wav)
+	if err != nil || f != audio.Mono16k || !bytes.Equal(got, pcm) {
+		t.Errorf("recorded %d bytes of %+v, %v; want %d bytes", len(got), f, err, len(pcm))
+	}
+	// 数据流中WAV头的长度为0，其余和Buffer相同
+	if !bytes.Equal(streamed[44:], wav[44:]) {
+		t.Error("stream differs from buffer")
+	}
+}
+
+// streamAfter 在d之后结束录音
+func streamAfter(stream <-chan []byte, d time.Duration, r *Recorder) <-chan []byte {
+	go func() {
+		time.Sleep(d)
+		r.Stop()
+	}()
+	return stream
+}
+
+func TestCommandBackend(t *testing.T) {
+	// 用sh模拟录音程序：先输出一些数据，然后等待被中断
+	b := commandBackend{name: "sh", bin: "sh", args: func(audio.Format) []string {
+		return []string{"-c", "printf 'abcd'; exec sleep 10"}
+	}}
+	if !b.Available() {
+		t.Skip("sh not found")
+	}
+
+	r := NewRecorder(b)
+	if err := r.Start(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	r.Stop()
+
+	if got := r.Buffer().Bytes()[44:]; string(got) != "abcd" {
+		t.Errorf("recorded %q", got)
+	}
+}
+
+func TestCommandBackendWAV(t *testing.T) {
+	// 设备不支持16k单声道，录音程序输出了48k双声道的WAV，实际格式以WAV头为准
+	stereo := audio.Format{SampleRate: 48000, Channels: 2, BitsPerSample: 16}
+	path := filepath.Join(t.TempDir(), "out.wav")
+	if err := os.WriteFile(path, append(audio.WAVHeader(stereo, 0), "abcd"...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	b := commandBackend{name: "sh", bin: "sh", wav: true, args: func(audio.Format) []string {
+		return []string{"-c", `cat "$0"; exec sleep 10`, path}
+	}}
+	if !b.Available() {
+		t.Skip("sh not found")
+	}
+
+	r := NewRecorder(b)
+	if err := r.Start(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	r.Stop()
+
+	if r.Format() != stereo {
+		t.Errorf("format = %+v, want %+v", r.Format(), stereo)
+	}
+	f, pcm, err := audio.ParseWAV(r.Buffer().Bytes())
+	if err != nil || f != stereo || string(pcm) != "abcd" {
+		t.Errorf("recorded %q as %+v, %v", pcm, f, err)
+	}
+
+	// 打不开设备时程序直接退出，没有WAV头
+	b.args = func(audio.Format) []string {
+		return []string{"-c", "echo 'no such device' >&2; exit 1"}
+	}
+	if _, _, err := b.Open(audio.Mono16k); err == nil || !strings.Contains(err.Error(), "no such device") {
+		t.Errorf("Open without header: err = %v", err)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	if _, err := Detect("no-such-backend"); err == nil {
+		t.Error("want error for unknown backend")
+	}
+	if _, err := Detect("file:/no/such/file.wav"); err == nil {
+		t.Error("want error for missing file")
+	}
+}
+
+func TestMeter(t *testing.T) {
+	levels := make(chan Level, 10)
+	m := newMeter(audio.Mono16k, levels)
+
+	// 100ms满幅度的方波，然后300ms静音，分成不整齐的几块写入
+	var pcm []byte
+	for i := 0; i < 1600; i++ {
+		v := int16(16384)
+		if i%2 == 1 {
+			v = -v
+		}
+		pcm = append(pcm, byte(uint16(v)), byte(uint16(v)>>8))
+	}
+	pcm = append(pcm, make([]byte, 9600)...)
+	for len(pcm) > 0 {
+		n := 1000
+		if n > len(pcm) {
+			n = len(pcm)
+		}
+		m.Write(pcm[:n])
+		pcm = pcm[n:]
+	}
+	close(levels)
+
+	var got []Level
+	for l := range levels {
+		got = append(got, l)
+	}
+	if len(got) != 4 {
+		t.Fatalf("got %d levels, want 4", len(got))
+	}
+	if got[0].Peak != 0.5 || got[0].RMS != 0.5 || got[0].Silence != 0 {
+		t.Errorf("first level = %+v", got[0])
+	}
+	if last := got[3]; last.Elapsed != 400*time.Millisecond || last.Silence != 300*time.Millisecond || last.Peak != 0 {
+		t.Errorf("last level = %+v", last)
+	}
+}
@@ -0,0 +1,152 @@
+package replay
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/tts"
+)
+
+// ErrNotFound 没有这个回答的语音，或者已经因为超出内存限制被丢掉了
+var ErrNotFound = errors.New("no audio for this message")
+
+// Store 按回答在聊天历史中的位置保存朗读的语音和字幕时间轴，用于重播。
+// 内存中的语音超出限制时，最早的先写到溢出目录；没有设置溢出目录时连同时间轴一起丢掉
+type Store struct {
+	mu     sync.Mutex
+	limit  int64  // 内存中最多保存多少字节，为0时不限制
+	dir    string // 溢出目录，为空时不溢出到磁盘
+	spill  string // 这次运行在dir下建的子目录，第一次溢出时才创建
+	clips  map[int]*clip
+	order  []int // 还在内存中的回答，按放入的先后排列
+	memory int64
+	last   int
+}
+
+type clip struct {
+	codec    string
+	audio    []byte // 溢出到磁盘后为nil
+	path     string // 溢出后的文件
+	timeline *tts.Timeline
+}
+
+// NewStore 内存中最多保存limit字节的语音，超出的写到dir下
+func NewStore(limit int64, dir string) *Store {
+	return &Store{limit: limit, dir: dir, clips: map[int]*clip{}, last: -1}
+}
+
+// Put 保存第message条聊天记录朗读的codec编码的语音和它的字幕时间轴，已有的会被替换
+func (s *Store) Put(message int, codec string, audio []byte, timeline *tts.Timeline) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remove(message)
+	s.clips[message] = &clip{codec: codec, audio: audio, timeline: timeline}
+	s.order = append(s.order, message)
+	s.memory += int64(len(audio))
+	s.last = message
+	return s.evict()
+}
+
+// Get 取出第message条聊天记录的语音和编码
+func (s *Store) Get(message int) ([]byte, string, error) {
+	s.mu.Lock()
+	c, ok := s.clips[message]
+	s.mu.Unlock()
+	if !ok {
+		return nil, "", ErrNotFound
+	}
+	if c.path == "" {
+		return c.audio, c.codec, nil
+	}
+	audio, err := os.ReadFile(c.path)
+	return audio, c.codec, err
+}
+
+// Timeline 第message条聊天记录的字幕时间轴，语音被丢掉后为nil
+func (s *Store) Timeline(message int) *tts.Timeline {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.clips[message]; ok {
+		return c.timeline
+	}
+	return nil
+}
+
+// Last 最近放入的语音对应的聊天记录位置
+func (s *Store) Last() (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.clips[s.last]
+	return s.last, ok
+}
+
+// Close 删除溢出到磁盘的语音
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.spill == "" {
+		return nil
+	}
+	return os.RemoveAll(s.spill)
+}
+
+// remove 去掉第message条的语音，调用时持有锁
+func (s *Store) remove(message int) {
+	c, ok := s.clips[message]
+	if !ok {
+		return
+	}
+	delete(s.clips, message)
+	if c.path != "" {
+		os.Remove(c.path)
+		return
+	}
+	s.memory -= int64(len(c.audio))
+	for i, m := range s.order {
+		if m == message {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// evict 超出内存限制时，把最早放入的语音溢出到磁盘或者丢掉，调用时持有锁
+func (s *Store) evict() error {
+	for s.limit > 0 && s.memory > s.limit && len(s.order) > 0 {
+		message := s.order[0]
+		s.order = s.order[1:]
+		c := s.clips[message]
+		s.memory -= int64(len(c.audio))
+
+		if s.dir == "" {
+			delete(s.clips, message)
+			continue
+		}
+		path, err := s.write(message, c)
+		if err != nil {
+			delete(s.clips, message)
+			return err
+		}
+		c.audio, c.path = nil, path
+	}
+	return nil
+}
+
+// write 把语音写到溢出目录
+func (s *Store) write(message int, c *clip) (string, error) {
+	if s.spill == "" {
+		if err := os.MkdirAll(s.dir, 0o755); err != nil {
+			return "", err
+		}
+		dir, err := os.MkdirTemp(s.dir, "replay-")
+		if err != nil {
+			return "", err
+		}
+		s.spill = dir
+	}
+	path := filepath.Join(s.spill, fmt.Sprintf("%d.%s", message, c.codec))
+	return path, os.WriteFile(path, c.audio, 0o644)
+}
@@ -0,0 +1,76 @@
+package replay
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/tts"
+)
+
+func TestStoreMemoryLimit(t *testing.T) {
+	// 没有溢出目录时，超出限制的最早的语音被丢掉
+	s := NewStore(10, "")
+	for i, audio := range [][]byte{[]byte("aaaa"), []byte("bbbb"), []byte("cccc")} {
+		if err := s.Put(i*2+1, "mp3", audio, tts.NewTimeline()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, _, err := s.Get(1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(1) err = %v, want ErrNotFound", err)
+	}
+	audio, codec, err := s.Get(5)
+	if err != nil || string(audio) != "cccc" || codec != "mp3" {
+		t.Errorf("Get(5) = %q, %q, %v", audio, codec, err)
+	}
+	// 时间轴和语音一起丢掉
+	if s.Timeline(1) != nil || s.Timeline(5) == nil {
+		t.Errorf("Timeline(1) = %v, Timeline(5) = %v; want only the kept one", s.Timeline(1), s.Timeline(5))
+	}
+	if last, ok := s.Last(); !ok || last != 5 {
+		t.Errorf("Last() = %d, %v, want 5", last, ok)
+	}
+}
+
+func TestStoreSpill(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(6, dir)
+	first, second := bytes.Repeat([]byte{1}, 5), bytes.Repeat([]byte{2}, 5)
+	if err := s.Put(1, "wav", first, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(3, "opus", second, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一个溢出到磁盘，仍然可以取出来
+	files, _ := filepath.Glob(filepath.Join(dir, "*", "1.wav"))
+	if len(files) != 1 {
+		t.Fatalf("spilled files = %v, want 1.wav", files)
+	}
+	audio, codec, err := s.Get(1)
+	if err != nil || !bytes.Equal(audio, first) || codec != "wav" {
+		t.Errorf("Get(1) = %v, %q, %v", audio, codec, err)
+	}
+
+	// 重新合成后替换，磁盘上的旧文件删掉
+	if err := s.Put(1, "wav", []byte{3}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(files[0]); !os.IsNotExist(err) {
+		t.Errorf("old spill file still exists: %v", err)
+	}
+	if audio, _, _ := s.Get(1); !bytes.Equal(audio, []byte{3}) {
+		t.Errorf("Get(1) after replace = %v", audio)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("spill directory not removed: %v", entries)
+	}
+}
@@ -0,0 +1,5 @@
+// Package speaker 通过oto播放到扬声器。
+//
+// oto在Linux上需要cgo和libasound，所以实现放在单独的包里并受构建标签控制：
+// CGO_ENABLED=0或者加上-tags nospeaker时这个包为空，其余的输出方式不受影响
+package speaker
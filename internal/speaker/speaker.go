@@ -0,0 +1,116 @@
+//go:build !nospeaker && (cgo || darwin || windows)
+
+package speaker
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ebitengine/oto/v3"
+	log "github.com/sirupsen/logrus"
+	myplayer "gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/player"
+)
+
+var (
+	otoContext *oto.Context
+	otoErr     error
+	once       sync.Once
+)
+
+// getOtoContext returns the singleton oto.Context
+func getOtoContext() (*oto.Context, error) {
+	once.Do(func() {
+		op := &oto.NewContextOptions{
+			SampleRate:   myplayer.OutputFormat.SampleRate,
+			ChannelCount: myplayer.OutputFormat.Channels,
+			Format:       oto.FormatSignedInt16LE,
+		}
+
+		var readyChan chan struct{}
+		otoContext, readyChan, otoErr = oto.NewContext(op)
+		if otoErr != nil {
+			return
+		}
+		<-readyChan
+	})
+	return otoContext, otoErr
+}
+
+// speaker 通过oto播放到扬声器
+type speaker struct{}
+
+// New 打开扬声器，没有声卡时返回错误
+func New() (myplayer.Sink, error) {
+	if _, err := getOtoContext(); err != nil {
+		return nil, err
+	}
+	return speaker{}, nil
+}
+
+// NewPlayer 打不开扬声器时不出声，只按时长播放，不影响其他功能
+func (speaker) NewPlayer(r io.Reader) myplayer.SinkPlayer {
+	ctx, err := getOtoContext()
+	if err != nil {
+		log.Warnf("打开扬声器失败，语音不会播放出来: %v", err)
+		return myplayer.NullSink{}.NewPlayer(r)
+	}
+	eof := &eofReader{r: r, eof: make(chan struct{})}
+	p := &player{Player: ctx.NewPlayer(eof), done: make(chan struct{}), closed: make(chan struct{})}
+	go p.wait(eof.eof)
+	return p
+}
+
+// eofReader 读到io.EOF时关闭eof
+type eofReader struct {
+	r    io.Reader
+	eof  chan struct{}
+	once sync.Once
+}
+
+func (e *eofReader) Read(b []byte) (int, error) {
+	n, err := e.r.Read(b)
+	if err == io.EOF {
+		e.once.Do(func() { close(e.eof) })
+	}
+	return n, err
+}
+
+// player 给*oto.Player加上播放完的通知
+type player struct {
+	*oto.Player
+	done      chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// checkInterval 数据都交给oto后，多久检查一次它的缓存是否播完了
+const checkInterval = 5 * time.Millisecond
+
+// wait oto没有播放结束的通知：读到结尾后，等它缓存的数据播完（暂停时缓存不会变空）再关闭done
+func (p *player) wait(eof <-chan struct{}) {
+	select {
+	case <-eof:
+	case <-p.closed:
+		return
+	}
+	t := time.NewTicker(checkInterval)
+	defer t.Stop()
+	for p.IsPlaying() || p.BufferedSize() > 0 {
+		select {
+		case <-t.C:
+		case <-p.closed:
+			return
+		}
+	}
+	close(p.done)
+}
+
+func (p *player) Done() <-chan struct{} {
+	return p.done
+}
+
+func (p *player) Close() error {
+	p.closeOnce.Do(func() { close(p.closed) })
+	return p.Player.Close()
+}
@@ -0,0 +1,308 @@
+package tts
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// 停顿标记，如 [pause]、[pause 500ms]、[停顿1s]
+var pauseMarker = regexp.MustCompile(`\[(?:pause|停顿)(?:[ :]?(\d+)(ms|s)?)?\]`)
+
+const defaultPause = 300 // ms
+
+// Lexicon 发音词典。合成前把每句话中的词替换成更容易读对的写法或拼音
+//
+// 文件每行一条规则，#开头为注释：
+//
+//	GPT => G P T
+//	/(\d+)k\b/ => ${1}千
+//	行长 => py:hang2 zhang3
+//
+// 两侧用/包住的是正则；py:开头的替换为拼音，需要后端支持SSML，否则保持原文
+type Lexicon struct {
+	path string
+
+	mu    sync.RWMutex
+	rules []lexiconRule
+}
+
+type lexiconRule struct {
+	re     *regexp.Regexp
+	repl   string
+	pinyin string
+}
+
+// LoadLexicon 从文件加载发音词典，文件不存在时返回空词典
+func LoadLexicon(path string) (*Lexicon, error) {
+	l := &Lexicon{path: path}
+	return l, l.Reload()
+}
+
+// Path 返回词典文件的路径
+func (l *Lexicon) Path() string {
+	return l.path
+}
+
+// Reload 重新读取词典文件，出错时保留原有规则
+func (l *Lexicon) Reload() error {
+	f, err := os.Open(l.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		l.mu.Lock()
+		l.rules = nil
+		l.mu.Unlock()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var rules []lexiconRule
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		r, err := parseLexiconRule(line)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", l.path, lineNo, err)
+		}
+		rules = append(rules, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.rules = rules
+	l.mu.Unlock()
+	return nil
+}
+
+func parseLexiconRule(line string) (lexiconRule, error) {
+	term, repl, ok := strings.Cut(line, "=>")
+	if !ok {
+		return lexiconRule{}, fmt.Errorf("missing '=>' in %q", line)
+	}
+	term, repl = strings.TrimSpace(term), strings.TrimSpace(repl)
+	if term == "" {
+		return lexiconRule{}, fmt.Errorf("empty term in %q", line)
+	}
+
+	var r lexiconRule
+	if len(term) > 2 && strings.HasPrefix(term, "/") && strings.HasSuffix(term, "/") {
+		re, err := regexp.Compile(term[1 : len(term)-1])
+		if err != nil {
+			return lexiconRule{}, err
+		}
+		r.re = re
+	} else {
+		r.re = regexp.MustCompile(regexp.QuoteMeta(term))
+	}
+
+	if py, ok := strings.CutPrefix(repl, "py:"); ok {
+		r.pinyin = strings.TrimSpace(py)
+	} else {
+		r.repl = repl
+	}
+	return r, nil
+}
+
+// Empty 词典是否没有任何规则
+func (l *Lexicon) Empty() bool {
+	if l == nil {
+		return true
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.rules) == 0
+}
+
+// Rendered 一句话应用词典和停顿标记后的结果
+type Rendered struct {
+	Text string // 交给合成的文本，SSML模式下为<speak>...</speak>
+
+	// 合成文本（不含SSML标签）中每个字对应原文中的[start, end)。
+	// 字幕的位置是按合成文本算的，用它换算回原文
+	spans [][2]int
+}
+
+// Original 把合成文本中第i个字的开始位置换算为原文中的位置
+func (r Rendered) Original(i int) int {
+	switch {
+	case len(r.spans) == 0:
+		return i
+	case i < 0:
+		return 0
+	case i >= len(r.spans):
+		return r.spans[len(r.spans)-1][1]
+	}
+	return r.spans[i][0]
+}
+
+// OriginalEnd 把合成文本中第i个字之前的结束位置换算为原文中的位置
+func (r Rendered) OriginalEnd(i int) int {
+	switch {
+	case len(r.spans) == 0:
+		return i
+	case i <= 0:
+		return r.Original(0)
+	case i > len(r.spans):
+		return r.spans[len(r.spans)-1][1]
+	}
+	return r.spans[i-1][1]
+}
+
+// Render 对一句话应用词典并展开停顿标记。ssml为true时输出SSML，否则输出纯文本
+func (l *Lexicon) Render(text string, ssml bool) Rendered {
+	var rules []lexiconRule
+	if l != nil {
+		l.mu.RLock()
+		rules = l.rules
+		l.mu.RUnlock()
+	}
+
+	b := renderBuilder{ssml: ssml}
+	pos, runePos := 0, 0
+	for pos < len(text) {
+		// 找到最靠前的匹配，位置相同时词典中靠前的规则优先
+		best, bestLoc := -1, []int(nil)
+		for i, r := range rules {
+			loc := r.re.FindStringSubmatchIndex(text[pos:])
+			if loc == nil || loc[1] == loc[0] {
+				continue
+			}
+			if bestLoc == nil || loc[0] < bestLoc[0] {
+				best, bestLoc = i, loc
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		start, end := pos+bestLoc[0], pos+bestLoc[1]
+		b.plain(text[pos:start], runePos)
+		runePos += utf8.RuneCountInString(text[pos:start])
+
+		matched := text[start:end]
+		matchedRunes := utf8.RuneCountInString(matched)
+		r := rules[best]
+		switch {
+		case r.pinyin != "" && ssml:
+			b.phoneme(matched, r.pinyin, runePos)
+		case r.pinyin != "":
+			b.plain(matched, runePos)
+		default:
+			repl := string(r.re.ExpandString(nil, r.repl, text[pos:], bestLoc))
+			b.replaced(repl, runePos, runePos+matchedRunes)
+		}
+		runePos += matchedRunes
+		pos = end
+	}
+	b.plain(text[pos:], runePos)
+
+	return b.done()
+}
+
+// StripPauses 去掉text中的停顿标记，用于显示。返回的函数把原文中的位置换算为去掉标记后的位置，
+// 和字幕一样按Render的对应关系换算，朗读的高亮不会错位
+func StripPauses(text string) (string, func(pos int) int) {
+	r := (*Lexicon)(nil).Render(text, false)
+	var b strings.Builder
+	var kept []int // 留下的每个字在原文中的位置
+	for i, c := range []rune(r.Text) {
+		// 停顿标记展开成的逗号不对应原文中的字
+		if span := r.spans[i]; span[0] < span[1] {
+			b.WriteRune(c)
+			kept = append(kept, span[0])
+		}
+	}
+	return b.String(), func(pos int) int { return sort.SearchInts(kept, pos) }
+}
+
+type renderBuilder struct {
+	ssml  bool
+	text  strings.Builder
+	spans [][2]int
+}
+
+// plain 原样输出一段原文（其中的停顿标记会被展开），start为这段文字在原文中的位置
+func (b *renderBuilder) plain(s string, start int) {
+	b.withPauses(s, func(offset int) [2]int { return [2]int{start + offset, start + offset + 1} })
+}
+
+// replaced 输出替换后的文字，它们都对应原文中被替换的[start, end)
+func (b *renderBuilder) replaced(s string, start, end int) {
+	b.withPauses(s, func(int) [2]int { return [2]int{start, end} })
+}
+
+func (b *renderBuilder) phoneme(s, pinyin string, start int) {
+	fmt.Fprintf(&b.text, `<phoneme alphabet="py" ph="%s">%s</phoneme>`, html.EscapeString(pinyin), html.EscapeString(s))
+	for i := 0; i < utf8.RuneCountInString(s); i++ {
+		b.spans = append(b.spans, [2]int{start + i, start + i + 1})
+	}
+}
+
+// withPauses 输出s并展开其中的停顿标记，orig把s中的位置换算为原文中的位置
+func (b *renderBuilder) withPauses(s string, orig func(offset int) [2]int) {
+	emit := func(from, to int) {
+		base := utf8.RuneCountInString(s[:from])
+		b.write(s[from:to])
+		for i := 0; i < utf8.RuneCountInString(s[from:to]); i++ {
+			b.spans = append(b.spans, orig(base+i))
+		}
+	}
+
+	offset := 0
+	for _, loc := range pauseMarker.FindAllStringSubmatchIndex(s, -1) {
+		emit(offset, loc[0])
+
+		ms := defaultPause
+		if loc[2] >= 0 {
+			ms, _ = strconv.Atoi(s[loc[2]:loc[3]])
+			if loc[4] >= 0 && s[loc[4]:loc[5]] == "s" {
+				ms *= 1000
+			}
+		}
+		if b.ssml {
+			fmt.Fprintf(&b.text, `<break time="%dms"/>`, ms)
+		} else {
+			// 不支持SSML时用逗号制造停顿
+			b.text.WriteString("，")
+			at := orig(utf8.RuneCountInString(s[:loc[0]]))
+			b.spans = append(b.spans, [2]int{at[0], at[0]})
+		}
+		offset = loc[1]
+	}
+	emit(offset, len(s))
+}
+
+func (b *renderBuilder) write(s string) {
+	if b.ssml {
+		b.text.WriteString(html.EscapeString(s))
+	} else {
+		b.text.WriteString(s)
+	}
+}
+
+func (b *renderBuilder) done() Rendered {
+	text := b.text.String()
+	if b.ssml {
+		text = "<speak>" + text + "</speak>"
+	}
+	return Rendered{Text: text, spans: b.spans}
+}
@@ -0,0 +1,73 @@
+package tts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLexiconRender(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lexicon.txt")
+	content := `# 测试词典
+GPT => G P T
+/(\d+)k/ => ${1}千
+行长 => py:hang2 zhang3
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := LoadLexicon(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		text string
+		ssml bool
+		want string
+	}{
+		{"用GPT写了5k字。", false, "用G P T写了5千字。"},
+		{"行长说[pause 500ms]好。", false, "行长说，好。"},
+		{"行长说[停顿]好。", true, `<speak><phoneme alphabet="py" ph="hang2 zhang3">行长</phoneme>说<break time="300ms"/>好。</speak>`},
+		{"a<b", true, "<speak>a&lt;b</speak>"},
+	}
+	for _, c := range cases {
+		if got := l.Render(c.text, c.ssml).Text; got != c.want {
+			t.Errorf("Render(%q, %v) = %q, want %q", c.text, c.ssml, got, c.want)
+		}
+	}
+
+	// 替换后的字幕位置要能换算回原文：“G P T”中的每个字都对应原文的“GPT”
+	r := l.Render("用GPT。", false)
+	if got := r.Original(3); got != 1 {
+		t.Errorf("Original(3) = %d, want 1", got)
+	}
+	if got := r.OriginalEnd(6); got != 4 {
+		t.Errorf("OriginalEnd(6) = %d, want 4", got)
+	}
+	if got := r.Original(6); got != 4 {
+		t.Errorf("Original(6) = %d, want 4", got)
+	}
+
+	if _, err := LoadLexicon(filepath.Join(t.TempDir(), "missing.txt")); err != nil {
+		t.Errorf("missing lexicon file should be ignored, got %v", err)
+	}
+}
+
+func TestStripPauses(t *testing.T) {
+	text := "好的[pause:500]，我们开始[停顿1s]吧。"
+	got, at := StripPauses(text)
+	if want := "好的，我们开始吧。"; got != want {
+		t.Errorf("StripPauses(%q) = %q, want %q", text, got, want)
+	}
+	// 原文中位置14的“我”去掉标记后在位置3；标记中的位置换算为它后面的字
+	for _, c := range []struct{ pos, want int }{{0, 0}, {2, 2}, {13, 2}, {14, 3}, {18, 7}, {24, 7}, {26, 9}} {
+		if got := at(c.pos); got != c.want {
+			t.Errorf("at(%d) = %d, want %d", c.pos, got, c.want)
+		}
+	}
+
+	if got, _ := StripPauses("没有标记。"); got != "没有标记。" {
+		t.Errorf("StripPauses without markers = %q", got)
+	}
+}
@@ -1,6 +1,8 @@
 package tts
 
 import (
+	"bytes"
+	"sort"
 	"sync"
 
 	"github.com/google/uuid"
@@ -13,8 +15,15 @@ import (
 type RealTimeSpeechSynthesizer struct {
 	SessionId string
 
+	Lexicon *Lexicon // 发音词典，为空时只展开停顿标记
+	SSML    bool     // 后端是否支持SSML，支持时拼音和停顿用SSML标签表达
+	Codec   string   // 返回的音频编码，为空时为mp3
+
 	audioStream chan<- []byte
 	total       int // 最长度，没啥用，打个日志
+	audio       bytes.Buffer
+	subtitles   map[int]Subtitle // BeginIndex -> 字幕，服务端可能重复下发
+	rendered    Rendered         // 最近一次实际交给合成的文本
 
 	appId      int64
 	credential *common.Credential
@@ -51,12 +60,16 @@ func (l *RealTimeSpeechSynthesizer) OnSynthesisEnd(r *tts.SpeechWsSynthesisRespo
 
 func (l *RealTimeSpeechSynthesizer) OnAudioResult(data []byte) {
 	l.audioStream <- data
+	l.audio.Write(data)
 	l.total += len(data)
 	// log.Debugf("OnAudioResult, len(data):%d total:%d\n", len(data), l.total)
 }
 
 func (l *RealTimeSpeechSynthesizer) OnTextResult(r *tts.SpeechWsSynthesisResponse) {
 	// log.Debugf("OnTextResult,sessionId:%s", l.SessionId)
+	for _, s := range r.Result.Subtitles {
+		l.subtitles[s.BeginIndex] = newSubtitle(s)
+	}
 }
 func (l *RealTimeSpeechSynthesizer) OnSynthesisFail(r *tts.SpeechWsSynthesisResponse, err error) {
 	log.Fatalf("OnSynthesisFail,sessionId:%s response: %s err:%s", l.SessionId, r.ToString(), err.Error())
@@ -65,6 +78,26 @@ func (l *RealTimeSpeechSynthesizer) OnSynthesisFail(r *tts.SpeechWsSynthesisResp
 func (l *RealTimeSpeechSynthesizer) Reset() {
 	l.SessionId = uuid.New().String()
 	l.total = 0
+	l.audio.Reset()
+	l.subtitles = make(map[int]Subtitle)
+}
+
+// Audio 返回最近一次Run合成的完整语音
+func (l *RealTimeSpeechSynthesizer) Audio() []byte {
+	return bytes.Clone(l.audio.Bytes())
+}
+
+// Subtitles 返回最近一次Run得到的字幕，按在句子中的位置排序
+// 字幕位置已换算为应用发音词典之前的原文中的位置
+func (l *RealTimeSpeechSynthesizer) Subtitles() []Subtitle {
+	subs := make([]Subtitle, 0, len(l.subtitles))
+	for _, s := range l.subtitles {
+		s.BeginIndex = l.rendered.Original(s.BeginIndex)
+		s.EndIndex = l.rendered.OriginalEnd(s.EndIndex)
+		subs = append(subs, s)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].BeginIndex < subs[j].BeginIndex })
+	return subs
 }
 func (l *RealTimeSpeechSynthesizer) Run(text string, audioStream chan<- []byte) {
 	log.Debug("开始转换语音: ", text, " voiceType:", l.voiceType, " emotionCategory:", l.emotionCategory)
@@ -72,6 +105,10 @@ func (l *RealTimeSpeechSynthesizer) Run(text string, audioStream chan<- []byte)
 
 	l.Reset()
 	l.audioStream = audioStream
+	l.rendered = l.Lexicon.Render(text, l.SSML)
+	if l.rendered.Text != text {
+		log.Debug("应用发音词典后: ", l.rendered.Text)
+	}
 
 	wg.Add(1)
 	go func() {
@@ -79,8 +116,12 @@ func (l *RealTimeSpeechSynthesizer) Run(text string, audioStream chan<- []byte)
 		synthesizer := tts.NewSpeechWsSynthesizer(l.appId, l.credential, l)
 		synthesizer.SessionId = l.SessionID()
 		synthesizer.VoiceType = l.voiceType
-		synthesizer.Codec = "mp3"
-		synthesizer.Text = text
+		synthesizer.SampleRate = SampleRate
+		synthesizer.Codec = l.Codec
+		if synthesizer.Codec == "" {
+			synthesizer.Codec = "mp3"
+		}
+		synthesizer.Text = l.rendered.Text
 		synthesizer.EnableSubtitle = true
 		synthesizer.Speed = l.speed // 1.5x
 		synthesizer.EmotionCategory = l.emotionCategory
@@ -0,0 +1,443 @@
+package tts
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+	"github.com/tencentcloud/tencentcloud-speech-sdk-go/tts"
+)
+
+// 腾讯云流式文本语音合成（文字可以分多次发送）的接口地址
+const DefaultStreamEndpoint = "wss://tts.cloud.tencent.com/stream_wsv2"
+
+const (
+	actionSynthesis = "ACTION_SYNTHESIS"
+	actionComplete  = "ACTION_COMPLETE"
+)
+
+// StreamConfig 流式合成会话的参数，相同参数的会话可以复用
+type StreamConfig struct {
+	AppID     int64
+	SecretID  string
+	SecretKey string
+
+	VoiceType       int64
+	EmotionCategory string
+	Speed           float64
+	Codec           string // 为空时为mp3
+
+	Endpoint string // 为空时为DefaultStreamEndpoint，测试时可指向本地服务
+}
+
+type streamRequest struct {
+	SessionID string `json:"session_id"`
+	MessageID string `json:"message_id"`
+	Action    string `json:"action"`
+	Data      string `json:"data"`
+}
+
+type streamResponse struct {
+	Code      int                    `json:"code"`
+	Message   string                 `json:"message"`
+	SessionID string                 `json:"session_id"`
+	RequestID string                 `json:"request_id"`
+	MessageID string                 `json:"message_id"`
+	Ready     int                    `json:"ready"`
+	Heartbeat int                    `json:"heartbeat"`
+	Final     int                    `json:"final"`
+	Result    tts.SynthesisSubtitles `json:"result"`
+}
+
+// StreamSession 一个流式合成会话：建立连接后可以多次发送文字，服务端边收边合成，
+// 省去了每句话一次握手和合成预热的时间
+type StreamSession struct {
+	Lexicon *Lexicon // 发音词典，流式合成不支持SSML，只做文字替换
+
+	sessionID string
+	conn      *websocket.Conn
+	created   time.Time
+
+	writeMu sync.Mutex
+
+	mu        sync.Mutex
+	audio     chan<- []byte
+	timeline  *Timeline
+	spans     [][2]int // 已发送文字中每个字对应回答中的位置
+	collected bytes.Buffer
+	subtitles []Subtitle
+	nextSub   int // 下一条还没处理的字幕在已发送文字中的位置
+
+	done chan struct{}
+	err  error
+}
+
+// DialStream 建立流式合成会话，等到服务端就绪后返回
+func DialStream(cfg StreamConfig) (*StreamSession, error) {
+	s := &StreamSession{
+		sessionID: uuid.New().String(),
+		created:   time.Now(),
+		done:      make(chan struct{}),
+	}
+
+	u, err := streamURL(cfg, s.sessionID)
+	if err != nil {
+		return nil, err
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("session_id: %s, dial: %w", s.sessionID, err)
+	}
+
+	// 等待服务端就绪
+	for {
+		var resp streamResponse
+		if err := conn.ReadJSON(&resp); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("session_id: %s, waiting ready: %w", s.sessionID, err)
+		}
+		if resp.Code != 0 {
+			conn.Close()
+			return nil, fmt.Errorf("session_id: %s, code: %d, message: %s", s.sessionID, resp.Code, resp.Message)
+		}
+		if resp.Ready == 1 {
+			break
+		}
+	}
+
+	s.conn = conn
+	go s.receive()
+	return s, nil
+}
+
+// streamURL 生成带签名的会话地址，签名方式和一句话一次的接口相同
+func streamURL(cfg StreamConfig, sessionID string) (string, error) {
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultStreamEndpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	codec := cfg.Codec
+	if codec == "" {
+		codec = "mp3"
+	}
+	now := time.Now().Unix()
+	params := map[string]string{
+		"Action":           "TextToStreamAudioWSv2",
+		"AppId":            strconv.FormatInt(cfg.AppID, 10),
+		"SecretId":         cfg.SecretID,
+		"Timestamp":        strconv.FormatInt(now, 10),
+		"Expired":          strconv.FormatInt(now+24*60*60, 10),
+		"SessionId":        sessionID,
+		"VoiceType":        strconv.FormatInt(cfg.VoiceType, 10),
+		"Codec":            codec,
+		"SampleRate":       strconv.Itoa(SampleRate),
+		"Speed":            strconv.FormatFloat(cfg.Speed, 'g', -1, 64),
+		"EnableSubtitle":   "true",
+		"EmotionCategory":  cfg.EmotionCategory,
+		"EmotionIntensity": "200",
+	}
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var query []string
+	for _, k := range keys {
+		query = append(query, k+"="+params[k])
+	}
+	raw := strings.Join(query, "&")
+
+	mac := hmac.New(sha1.New, []byte(cfg.SecretKey))
+	mac.Write([]byte("GET" + u.Host + u.Path + "?" + raw))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	values := url.Values{}
+	for k, v := range params {
+		values.Set(k, v)
+	}
+	values.Set("Signature", signature)
+	u.RawQuery = values.Encode()
+	return u.String(), nil
+}
+
+// Bind 设置语音数据的去处。timeline不为空时，收到的字幕会实时追加进去
+// 必须在Send之前调用
+func (s *StreamSession) Bind(audio chan<- []byte, timeline *Timeline) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.audio = audio
+	s.timeline = timeline
+}
+
+// Send 发送一段文字，offset为它在整个回答中的位置（按rune计）
+// 会话已经结束（服务端报错或断开）时返回错误，这段文字没有发出去
+func (s *StreamSession) Send(text string, offset int) error {
+	select {
+	case <-s.done:
+		if s.err != nil {
+			return s.err
+		}
+		return fmt.Errorf("session_id: %s, 会话已结束", s.sessionID)
+	default:
+	}
+	r := s.Lexicon.Render(text, false)
+
+	s.mu.Lock()
+	n := utf8.RuneCountInString(r.Text)
+	for i := 0; i < n; i++ {
+		s.spans = append(s.spans, [2]int{offset + r.Original(i), offset + r.OriginalEnd(i+1)})
+	}
+	s.mu.Unlock()
+
+	return s.write(actionSynthesis, r.Text)
+}
+
+// Complete 告诉服务端文字已经发完，等待剩余的语音合成完毕
+func (s *StreamSession) Complete() error {
+	if err := s.write(actionComplete, ""); err != nil {
+		return err
+	}
+	<-s.done
+	return s.err
+}
+
+// Close 放弃会话
+func (s *StreamSession) Close() {
+	s.conn.Close()
+	<-s.done
+}
+
+// Alive 会话是否还能使用
+func (s *StreamSession) Alive() bool {
+	select {
+	case <-s.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Audio 返回会话中合成的全部语音
+func (s *StreamSession) Audio() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return bytes.Clone(s.collected.Bytes())
+}
+
+// Subtitles 返回会话中的全部字幕，位置已换算为回答中的位置
+func (s *StreamSession) Subtitles() []Subtitle {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Subtitle(nil), s.subtitles...)
+}
+
+func (s *StreamSession) write(action, data string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.conn.WriteJSON(streamRequest{
+		SessionID: s.sessionID,
+		MessageID: uuid.New().String(),
+		Action:    action,
+		Data:      data,
+	})
+}
+
+func (s *StreamSession) receive() {
+	defer close(s.done)
+	defer s.conn.Close()
+
+	for {
+		typ, data, err := s.conn.ReadMessage()
+		if err != nil {
+			s.err = fmt.Errorf("session_id: %s, %w", s.sessionID, err)
+			return
+		}
+
+		if typ == websocket.BinaryMessage {
+			s.mu.Lock()
+			audio := s.audio
+			s.collected.Write(data)
+			s.mu.Unlock()
+			if audio != nil {
+				audio <- data
+			}
+			continue
+		}
+
+		var resp streamResponse
+		if err := json.Unmarshal(data, &resp); err != nil {
+			s.err = fmt.Errorf("session_id: %s, %w", s.sessionID, err)
+			return
+		}
+		if resp.Code != 0 {
+			s.err = fmt.Errorf("session_id: %s, code: %d, message: %s", s.sessionID, resp.Code, resp.Message)
+			return
+		}
+		s.onSubtitles(resp.Result.Subtitles)
+		if resp.Final == 1 {
+			return
+		}
+	}
+}
+
+// onSubtitles 处理新收到的字幕，服务端可能重复下发之前的字幕
+func (s *StreamSession) onSubtitles(subs []tts.SynthesisSubtitle) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var fresh []Subtitle
+	for _, sub := range subs {
+		if sub.BeginIndex < s.nextSub {
+			continue
+		}
+		st := newSubtitle(sub)
+		st.BeginIndex = s.original(sub.BeginIndex, 0)
+		st.EndIndex = s.original(sub.EndIndex-1, 1)
+		fresh = append(fresh, st)
+		s.nextSub = sub.EndIndex
+	}
+	s.subtitles = append(s.subtitles, fresh...)
+	if s.timeline != nil && len(fresh) > 0 {
+		s.timeline.Append(0, 0, fresh)
+	}
+}
+
+func (s *StreamSession) original(i, side int) int {
+	if len(s.spans) == 0 {
+		return 0
+	}
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(s.spans) {
+		i = len(s.spans) - 1
+	}
+	return s.spans[i][side]
+}
+
+// SessionPool 预先建立好的流式合成会话，拿来就能用，省去握手的时间
+type SessionPool struct {
+	size int
+	ttl  time.Duration // 空闲会话的最长保留时间，过期的会被服务端断开
+
+	mu   sync.Mutex
+	idle map[StreamConfig][]*StreamSession
+}
+
+// NewSessionPool 每种参数保留size个空闲会话
+func NewSessionPool(size int, ttl time.Duration) *SessionPool {
+	return &SessionPool{
+		size: size,
+		ttl:  ttl,
+		idle: make(map[StreamConfig][]*StreamSession),
+	}
+}
+
+// Get 取一个可用的会话，没有空闲会话时现场建立。取走后在后台补充
+func (p *SessionPool) Get(cfg StreamConfig) (*StreamSession, error) {
+	defer p.Warm(cfg)
+
+	p.mu.Lock()
+	for len(p.idle[cfg]) > 0 {
+		s := p.idle[cfg][0]
+		p.idle[cfg] = p.idle[cfg][1:]
+		if s.Alive() && time.Since(s.created) < p.ttl {
+			p.mu.Unlock()
+			return s, nil
+		}
+		go s.Close()
+	}
+	p.mu.Unlock()
+
+	return DialStream(cfg)
+}
+
+// Warm 在后台把空闲会话补充到size个
+func (p *SessionPool) Warm(cfg StreamConfig) {
+	p.mu.Lock()
+	missing := p.size - len(p.idle[cfg])
+	p.mu.Unlock()
+
+	for i := 0; i < missing; i++ {
+		go func() {
+			s, err := DialStream(cfg)
+			if err != nil {
+				log.Warnf("预建立语音合成会话失败: %v", err)
+				return
+			}
+
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			if len(p.idle[cfg]) >= p.size {
+				go s.Close()
+				return
+			}
+			p.idle[cfg] = append(p.idle[cfg], s)
+		}()
+	}
+}
+
+// Synthesizer 返回一个每句话使用池中一个会话的合成器，用法和RealTimeSpeechSynthesizer一样
+func (p *SessionPool) Synthesizer(cfg StreamConfig, lexicon *Lexicon) *PooledSynthesizer {
+	return &PooledSynthesizer{pool: p, cfg: cfg, lexicon: lexicon}
+}
+
+// PooledSynthesizer 一句话一个会话，但会话是预先建立好的
+type PooledSynthesizer struct {
+	pool    *SessionPool
+	cfg     StreamConfig
+	lexicon *Lexicon
+	last    *StreamSession
+}
+
+// Run 合成一句话，语音写入audioStream
+func (l *PooledSynthesizer) Run(text string, audioStream chan<- []byte) {
+	l.last = nil
+	s, err := l.pool.Get(l.cfg)
+	if err == nil {
+		s.Lexicon = l.lexicon
+		s.Bind(audioStream, nil)
+		if err = s.Send(text, 0); err == nil {
+			err = s.Complete()
+		}
+	}
+	if err != nil {
+		log.Errorf("语音合成失败: %v", err)
+	}
+	l.last = s
+}
+
+// Audio 返回最近一次Run合成的完整语音
+func (l *PooledSynthesizer) Audio() []byte {
+	if l.last == nil {
+		return nil
+	}
+	return l.last.Audio()
+}
+
+// Subtitles 返回最近一次Run得到的字幕
+func (l *PooledSynthesizer) Subtitles() []Subtitle {
+	if l.last == nil {
+		return nil
+	}
+	return l.last.Subtitles()
+}
@@ -0,0 +1,272 @@
+package tts
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+	"github.com/tencentcloud/tencentcloud-speech-sdk-go/tts"
+)
+
+// fakeStreamServer 本地模拟的流式合成服务：握手和每个会话的首次合成都有固定耗时，
+// 每个字返回一段“语音”和一条字幕
+type fakeStreamServer struct {
+	handshake time.Duration // 建立连接到就绪的耗时
+	warmup    time.Duration // 会话第一次合成的预热耗时
+	failAfter int           // 大于0时，收到这么多段文字后返回错误
+}
+
+func (f fakeStreamServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	time.Sleep(f.handshake)
+	conn.WriteJSON(streamResponse{Ready: 1})
+
+	warm := false
+	index := 0
+	for {
+		var req streamRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		if req.Action == actionComplete {
+			conn.WriteJSON(streamResponse{Final: 1})
+			return
+		}
+
+		if f.failAfter > 0 && req.Action == actionSynthesis {
+			if f.failAfter--; f.failAfter == 0 {
+				conn.WriteJSON(streamResponse{Code: 10001, Message: "synthesis failed"})
+				return
+			}
+		}
+		if !warm {
+			time.Sleep(f.warmup)
+			warm = true
+		}
+		var subs []tts.SynthesisSubtitle
+		for _, r := range req.Data {
+			conn.WriteMessage(websocket.BinaryMessage, []byte(string(r)))
+			subs = append(subs, tts.SynthesisSubtitle{
+				Text:       string(r),
+				BeginTime:  int64(index) * 100,
+				EndTime:    int64(index+1) * 100,
+				BeginIndex: index,
+				EndIndex:   index + 1,
+			})
+			index++
+		}
+		conn.WriteJSON(streamResponse{Result: tts.SynthesisSubtitles{Subtitles: subs}})
+	}
+}
+
+func startFakeStreamServer(t testing.TB, f fakeStreamServer) StreamConfig {
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return StreamConfig{
+		AppID:     1,
+		SecretID:  "id",
+		SecretKey: "key",
+		VoiceType: 101016,
+		Speed:     1,
+		Endpoint:  "ws" + strings.TrimPrefix(srv.URL, "http") + "/stream_wsv2",
+	}
+}
+
+func TestStreamSession(t *testing.T) {
+	cfg := startFakeStreamServer(t, fakeStreamServer{})
+
+	path := filepath.Join(t.TempDir(), "lexicon.txt")
+	if err := os.WriteFile(path, []byte("AI => 人工智能\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	lexicon, err := LoadLexicon(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := DialStream(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Lexicon = lexicon
+	audio := make(chan []byte, 100)
+	timeline := NewTimeline()
+	s.Bind(audio, timeline)
+
+	// 回答是“你好，AI。”，分两次发送
+	if err := s.Send("你好，", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Send("AI。", 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Complete(); err != nil {
+		t.Fatal(err)
+	}
+	close(audio)
+
+	var got strings.Builder
+	for data := range audio {
+		got.Write(data)
+	}
+	if want := "你好，人工智能。"; got.String() != want {
+		t.Errorf("audio = %q, want %q", got.String(), want)
+	}
+
+	// “人工智能”这几个字都对应回答中的“AI”
+	subs := s.Subtitles()
+	if len(subs) != 8 {
+		t.Fatalf("got %d subtitles, want 8", len(subs))
+	}
+	if subs[4].BeginIndex != 3 || subs[4].EndIndex != 5 {
+		t.Errorf("subtitle %q maps to [%d, %d), want [3, 5)", subs[4].Text, subs[4].BeginIndex, subs[4].EndIndex)
+	}
+	if cue, ok := timeline.At(750 * time.Millisecond); !ok || cue.Start != 5 {
+		t.Errorf("timeline.At = %+v, %v; want start 5", cue, ok)
+	}
+}
+
+func TestStreamSessionFailed(t *testing.T) {
+	cfg := startFakeStreamServer(t, fakeStreamServer{failAfter: 2})
+	s, err := DialStream(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Bind(make(chan []byte, 100), nil)
+
+	if err := s.Send("你好，", 0); err != nil {
+		t.Fatal(err)
+	}
+	s.Send("今天", 3) // 服务端收到后报错
+	deadline := time.Now().Add(time.Second)
+	for s.Alive() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// 会话已经断开，后面的文字不能再假装发送成功
+	if err := s.Send("天气不错。", 5); err == nil || !strings.Contains(err.Error(), "synthesis failed") {
+		t.Errorf("Send after failure = %v", err)
+	}
+}
+
+func TestSessionPool(t *testing.T) {
+	cfg := startFakeStreamServer(t, fakeStreamServer{})
+	pool := NewSessionPool(1, time.Minute)
+	pool.Warm(cfg)
+
+	for i := 0; i < 3; i++ {
+		syn := pool.Synthesizer(cfg, nil)
+		audio := make(chan []byte, 100)
+		syn.Run("你好。", audio)
+		if got := string(syn.Audio()); got != "你好。" {
+			t.Errorf("run %d: audio = %q", i, got)
+		}
+		if n := len(syn.Subtitles()); n != 3 {
+			t.Errorf("run %d: got %d subtitles, want 3", i, n)
+		}
+	}
+}
+
+var benchSentences = []string{"床前明月光，", "疑是地上霜。", "举头望明月，", "低头思故乡。", "这是李白的静夜思。"}
+
+// 模拟真实服务：握手30ms，预热50ms
+var benchServer = fakeStreamServer{handshake: 30 * time.Millisecond, warmup: 50 * time.Millisecond}
+
+// reportFirstAudio 报告从开始到收到第一段语音的平均耗时
+func reportFirstAudio(b *testing.B, total time.Duration) {
+	b.ReportMetric(float64(total.Milliseconds())/float64(b.N), "ms/first-audio")
+}
+
+// BenchmarkPerSentenceDial 每句话建立一个新的流式会话，和原来一句话一次连接的做法一样，
+// 每句都要握手和预热。原来的接口无法指向本地服务，所以用流式会话模拟
+func BenchmarkPerSentenceDial(b *testing.B) {
+	cfg := startFakeStreamServer(b, benchServer)
+	var total time.Duration
+	for i := 0; i < b.N; i++ {
+		start := time.Now()
+		for j, text := range benchSentences {
+			s, err := DialStream(cfg)
+			if err != nil {
+				b.Fatal(err)
+			}
+			audio := make(chan []byte, 100)
+			s.Bind(audio, nil)
+			s.Send(text, 0)
+			if j == 0 {
+				<-audio
+				total += time.Since(start)
+			}
+			if err := s.Complete(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	reportFirstAudio(b, total)
+}
+
+// BenchmarkStreamingSession 一轮对话一个会话，文字边到边发
+func BenchmarkStreamingSession(b *testing.B) {
+	cfg := startFakeStreamServer(b, benchServer)
+	var total time.Duration
+	for i := 0; i < b.N; i++ {
+		start := time.Now()
+		s, err := DialStream(cfg)
+		if err != nil {
+			b.Fatal(err)
+		}
+		audio := make(chan []byte, 1000)
+		s.Bind(audio, nil)
+		offset := 0
+		for _, text := range benchSentences {
+			s.Send(text, offset)
+			offset += utf8.RuneCountInString(text)
+		}
+		<-audio
+		total += time.Since(start)
+		if err := s.Complete(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	reportFirstAudio(b, total)
+}
+
+// BenchmarkPooledStreamingSession 一轮对话一个会话，并且会话是预先建立好的
+func BenchmarkPooledStreamingSession(b *testing.B) {
+	cfg := startFakeStreamServer(b, benchServer)
+	pool := NewSessionPool(1, time.Minute)
+	pool.Warm(cfg)
+	time.Sleep(100 * time.Millisecond)
+
+	var total time.Duration
+	for i := 0; i < b.N; i++ {
+		start := time.Now()
+		s, err := pool.Get(cfg)
+		if err != nil {
+			b.Fatal(err)
+		}
+		audio := make(chan []byte, 1000)
+		s.Bind(audio, nil)
+		offset := 0
+		for _, text := range benchSentences {
+			s.Send(text, offset)
+			offset += utf8.RuneCountInString(text)
+		}
+		<-audio
+		total += time.Since(start)
+		if err := s.Complete(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	reportFirstAudio(b, total)
+}
@@ -0,0 +1,122 @@
+package tts
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tencentcloud/tencentcloud-speech-sdk-go/tts"
+)
+
+// Subtitle 一句话中某个字（或词）的朗读时间，时间相对于这句话语音的开头
+type Subtitle struct {
+	Text       string
+	Begin      time.Duration
+	End        time.Duration
+	BeginIndex int // 在句子中的起始位置（按rune计）
+	EndIndex   int // 在句子中的结束位置（不含）
+}
+
+func newSubtitle(s tts.SynthesisSubtitle) Subtitle {
+	return Subtitle{
+		Text:       s.Text,
+		Begin:      time.Duration(s.BeginTime) * time.Millisecond,
+		End:        time.Duration(s.EndTime) * time.Millisecond,
+		BeginIndex: s.BeginIndex,
+		EndIndex:   s.EndIndex,
+	}
+}
+
+// Cue 整个回答中的一段朗读：播放到[Begin, End)时，正在读回答中的[Start, Stop)这些字
+type Cue struct {
+	Begin time.Duration
+	End   time.Duration
+	Start int
+	Stop  int
+}
+
+// Timeline 把每句话的字幕按播放顺序串起来，配合播放进度就能知道当前在读哪个字
+type Timeline struct {
+	mu     sync.Mutex
+	cues   []Cue
+	offset time.Duration // 已追加句子的语音总时长
+
+	starts []time.Duration // 逐句合成时每句话语音开始的时间
+	marks  []int           // 流式合成时每句话在回答中开始的位置
+}
+
+// 跳到下一句时，离得太近的句子也跳过，免得按了像没反应
+const sentenceGap = 300 * time.Millisecond
+
+func NewTimeline() *Timeline {
+	return &Timeline{}
+}
+
+// Append 追加一句话的字幕。textOffset为这句话在整个回答中的位置，duration为这句话语音的时长
+func (t *Timeline) Append(textOffset int, duration time.Duration, subs []Subtitle) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if duration > 0 {
+		t.starts = append(t.starts, t.offset)
+	}
+	for _, s := range subs {
+		t.cues = append(t.cues, Cue{
+			Begin: t.offset + s.Begin,
+			End:   t.offset + s.End,
+			Start: textOffset + s.BeginIndex,
+			Stop:  textOffset + s.EndIndex,
+		})
+	}
+	t.offset += duration
+}
+
+// At 返回播放到pos时正在朗读的那一段，两个字之间的停顿算作前一个字
+func (t *Timeline) At(pos time.Duration) (Cue, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	i := sort.Search(len(t.cues), func(i int) bool { return t.cues[i].Begin > pos })
+	if i == 0 {
+		return Cue{}, false
+	}
+
+	c := t.cues[i-1]
+	if i == len(t.cues) && pos >= c.End {
+		return Cue{}, false
+	}
+	return c, true
+}
+
+// MarkSentence 流式合成时标记一句话在回答中开始的位置，这句话的第一个字朗读时就是句子的开始
+func (t *Timeline) MarkSentence(textOffset int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.marks = append(t.marks, textOffset)
+}
+
+// NextSentence 返回播放到pos时，下一句话开始的时间。还没有下一句时返回false
+func (t *Timeline) NextSentence(pos time.Duration) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var next time.Duration
+	found := false
+	consider := func(d time.Duration) {
+		if d > pos+sentenceGap && (!found || d < next) {
+			next, found = d, true
+		}
+	}
+	for _, d := range t.starts {
+		consider(d)
+	}
+	for _, m := range t.marks {
+		for _, c := range t.cues {
+			if c.Start >= m {
+				consider(c.Begin)
+				break
+			}
+		}
+	}
+	return next, found
+}
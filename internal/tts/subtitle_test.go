@@ -0,0 +1,78 @@
+package tts
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeline(t *testing.T) {
+	tl := NewTimeline()
+	// 第一句“你好。”，语音时长1秒
+	tl.Append(0, time.Second, []Subtitle{
+		{Text: "你", Begin: 100 * time.Millisecond, End: 300 * time.Millisecond, BeginIndex: 0, EndIndex: 1},
+		{Text: "好", Begin: 300 * time.Millisecond, End: 500 * time.Millisecond, BeginIndex: 1, EndIndex: 2},
+	})
+	// 第二句“再见。”在回答中从第3个字开始
+	tl.Append(3, time.Second, []Subtitle{
+		{Text: "再", Begin: 0, End: 200 * time.Millisecond, BeginIndex: 0, EndIndex: 1},
+		{Text: "见", Begin: 200 * time.Millisecond, End: 400 * time.Millisecond, BeginIndex: 1, EndIndex: 2},
+	})
+
+	cases := []struct {
+		pos   time.Duration
+		ok    bool
+		start int
+	}{
+		{0, false, 0},
+		{150 * time.Millisecond, true, 0},
+		{400 * time.Millisecond, true, 1},
+		{800 * time.Millisecond, true, 1}, // 句间停顿仍然高亮上一个字
+		{1100 * time.Millisecond, true, 3},
+		{1300 * time.Millisecond, true, 4},
+		{2 * time.Second, false, 0},
+	}
+	for _, c := range cases {
+		cue, ok := tl.At(c.pos)
+		if ok != c.ok || (ok && cue.Start != c.start) {
+			t.Errorf("At(%v) = %+v, %v; want start %d, %v", c.pos, cue, ok, c.start, c.ok)
+		}
+	}
+}
+
+func TestNextSentence(t *testing.T) {
+	// 逐句合成：每句话语音开始的时间
+	tl := NewTimeline()
+	tl.Append(0, time.Second, nil)
+	tl.Append(3, 2*time.Second, nil)
+	tl.Append(6, time.Second, nil)
+
+	cases := []struct {
+		pos  time.Duration
+		next time.Duration
+		ok   bool
+	}{
+		{0, time.Second, true},
+		{900 * time.Millisecond, 3 * time.Second, true}, // 离下一句太近，跳到再下一句
+		{time.Second, 3 * time.Second, true},
+		{3 * time.Second, 0, false},
+	}
+	for _, c := range cases {
+		next, ok := tl.NextSentence(c.pos)
+		if next != c.next || ok != c.ok {
+			t.Errorf("NextSentence(%v) = %v, %v; want %v, %v", c.pos, next, ok, c.next, c.ok)
+		}
+	}
+
+	// 流式合成：句子开始的位置对应第一个字的朗读时间
+	tl = NewTimeline()
+	tl.MarkSentence(0)
+	tl.MarkSentence(3)
+	tl.Append(0, 0, []Subtitle{
+		{Text: "你", Begin: 0, End: 200 * time.Millisecond, BeginIndex: 0, EndIndex: 1},
+		{Text: "好", Begin: 200 * time.Millisecond, End: 400 * time.Millisecond, BeginIndex: 1, EndIndex: 2},
+		{Text: "再", Begin: 900 * time.Millisecond, End: 1100 * time.Millisecond, BeginIndex: 3, EndIndex: 4},
+	})
+	if next, ok := tl.NextSentence(100 * time.Millisecond); !ok || next != 900*time.Millisecond {
+		t.Errorf("NextSentence() = %v, %v; want 900ms", next, ok)
+	}
+}
@@ -0,0 +1,100 @@
+package tts
+
+import (
+	_ "embed"
+	"encoding/json"
+)
+
+// 音色目录，来自腾讯云语音合成的音色列表，新增音色时修改voices.json即可
+//
+//go:embed voices.json
+var voicesJSON []byte
+
+var voices []VoiceInfo
+
+func init() {
+	if err := json.Unmarshal(voicesJSON, &voices); err != nil {
+		panic(err)
+	}
+}
+
+// EmotionNames 情感的中文名
+var EmotionNames = map[string]string{
+	"neutral":   "中性",
+	"sad":       "悲伤",
+	"happy":     "高兴",
+	"angry":     "生气",
+	"fear":      "恐惧",
+	"news":      "新闻",
+	"story":     "故事",
+	"radio":     "广播",
+	"poetry":    "诗歌",
+	"call":      "客服",
+	"sajiao":    "撒娇",
+	"disgusted": "厌恶",
+	"amaze":     "震惊",
+	"peaceful":  "平静",
+	"exciting":  "兴奋",
+	"aojiao":    "傲娇",
+	"jieshuo":   "解说",
+}
+
+// VoiceInfo 一个音色的能力说明，SampleRates为音色支持的合成采样率
+type VoiceInfo struct {
+	ID          int64    `json:"id"`
+	Name        string   `json:"name"`
+	Desc        string   `json:"desc"`
+	Language    string   `json:"language"`
+	Gender      string   `json:"gender"`
+	SampleRates []int    `json:"sample_rates"`
+	Emotions    []string `json:"emotions"`
+}
+
+// SampleRate 合成时请求的采样率，播放和存档都按这个采样率处理
+const SampleRate = 16000
+
+// Voices 返回所有音色
+func Voices() []VoiceInfo {
+	return voices
+}
+
+// VoicesFor 返回支持采样率rate的音色
+func VoicesFor(rate int) []VoiceInfo {
+	var list []VoiceInfo
+	for _, v := range voices {
+		if v.SupportsSampleRate(rate) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// LookupVoice 按ID查找音色
+func LookupVoice(id int64) (VoiceInfo, bool) {
+	for _, v := range voices {
+		if v.ID == id {
+			return v, true
+		}
+	}
+	return VoiceInfo{}, false
+}
+
+// SupportsSampleRate 音色是否支持某个采样率
+func (v VoiceInfo) SupportsSampleRate(rate int) bool {
+	for _, r := range v.SampleRates {
+		if r == rate {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsEmotion 音色是否支持某种情感
+func (v VoiceInfo) SupportsEmotion(emotion string) bool {
+	for _, e := range v.Emotions {
+		if e == emotion {
+			return true
+		}
+	}
+	return false
+}
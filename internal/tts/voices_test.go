@@ -0,0 +1,66 @@
+package tts
+
+import "testing"
+
+func TestVoices(t *testing.T) {
+	if len(Voices()) == 0 {
+		t.Fatal("voices.json is empty")
+	}
+	seen := map[int64]bool{}
+	for _, v := range Voices() {
+		if seen[v.ID] {
+			t.Errorf("duplicate voice %d", v.ID)
+		}
+		seen[v.ID] = true
+		if v.Name == "" || v.Language == "" || v.Gender == "" {
+			t.Errorf("voice %d is missing name, language or gender: %+v", v.ID, v)
+		}
+		if len(v.SampleRates) == 0 {
+			t.Errorf("voice %d has no sample rates", v.ID)
+		}
+		if !v.SupportsEmotion("neutral") {
+			t.Errorf("voice %d does not support neutral", v.ID)
+		}
+		for _, e := range v.Emotions {
+			if _, ok := EmotionNames[e]; !ok {
+				t.Errorf("voice %d has unknown emotion %q", v.ID, e)
+			}
+		}
+	}
+}
+
+func TestLookupVoice(t *testing.T) {
+	v, ok := LookupVoice(101016)
+	if !ok || v.Name != "智甜" {
+		t.Fatalf("LookupVoice(101016) = %+v, %v", v, ok)
+	}
+	if !v.SupportsEmotion("sajiao") || v.SupportsEmotion("sad") {
+		t.Errorf("101016 emotions = %v", v.Emotions)
+	}
+	if _, ok := LookupVoice(1); ok {
+		t.Error("LookupVoice(1) should fail")
+	}
+}
+
+func TestVoicesFor(t *testing.T) {
+	defer func(old []VoiceInfo) { voices = old }(voices)
+	voices = []VoiceInfo{
+		{ID: 1, SampleRates: []int{8000, 16000}},
+		{ID: 2, SampleRates: []int{8000}}, // 只支持8k，不能用于16k合成
+		{ID: 3, SampleRates: []int{8000, 16000, 24000}},
+	}
+
+	var ids []int64
+	for _, v := range VoicesFor(16000) {
+		ids = append(ids, v.ID)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 3 {
+		t.Errorf("VoicesFor(16000) = %v, want [1 3]", ids)
+	}
+	if got := VoicesFor(24000); len(got) != 1 || got[0].ID != 3 {
+		t.Errorf("VoicesFor(24000) = %+v, want voice 3", got)
+	}
+	if voices[1].SupportsSampleRate(SampleRate) {
+		t.Error("voice 2 should not support the synthesis sample rate")
+	}
+}
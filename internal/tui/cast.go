@@ -0,0 +1,57 @@
+package tui
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/persona"
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/tts"
+)
+
+// Cast 当前的角色扮演设定，Colors为角色名到颜色的映射
+type Cast struct {
+	Name   string            `json:"name"`
+	Colors map[string]string `json:"colors"`
+}
+
+// renderAssistant 渲染一条需要逐字设置样式的回答：角色扮演时每行用角色的颜色，正在朗读的文字高亮
+// 返回渲染后的内容，以及正在朗读的文字所在的行（没有时为-1）
+func (m *model) renderAssistant(index int, content string, textWidth int) (string, int) {
+	// 朗读的位置是按原文算的，去掉停顿标记后要换算
+	content, at := tts.StripPauses(content)
+	start, stop := at(m.speaking.Start), at(m.speaking.Stop)
+	styles := []lipgloss.Style{assistantTextStyle, speakingStyle}
+	classes := make([]int, 0, utf8.RuneCountInString(content))
+
+	colorClass := map[string]int{}
+	for _, line := range strings.SplitAfter(content, "\n") {
+		class := 0
+		if name, _, ok := persona.Tag(line); ok {
+			if color, known := m.cast.Colors[name]; known {
+				c, seen := colorClass[color]
+				if !seen {
+					c = len(styles)
+					styles = append(styles, assistantTextStyle.Background(lipgloss.Color(color)))
+					colorClass[color] = c
+				}
+				class = c
+			}
+		}
+		for range line {
+			classes = append(classes, class)
+		}
+	}
+
+	mark := -1
+	if index == m.speaking.Message {
+		mark = start
+	}
+	classOf := func(i int) int {
+		if i >= start && i < stop && index == m.speaking.Message {
+			return 1
+		}
+		return classes[i]
+	}
+	return WrapWordsStyled(content, textWidth, styles, classOf, mark)
+}
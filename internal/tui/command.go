@@ -0,0 +1,76 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type lexiconEditedMsg struct{ err error }
+
+// runCommand 处理输入框中以/开头的命令
+func (m model) runCommand(input string) (tea.Model, tea.Cmd) {
+	args := strings.Fields(input)
+	switch args[0] {
+	case "/lexicon":
+		if len(args) > 1 && args[1] == "reload" {
+			m.eventChan <- Event{Type: "lexicon_reload"}
+			return m, nil
+		}
+		return m, m.editLexicon()
+	case "/persona":
+		// 不带名字时取消角色扮演
+		m.eventChan <- Event{Type: "persona", Payload: strings.Join(args[1:], " ")}
+		return m, nil
+	case "/audio":
+		// 把音频文件识别后作为问题
+		if len(args) < 2 {
+			m.notification = "用法: /audio 文件路径"
+			return m, m.clearNotification()
+		}
+		m.eventChan <- Event{Type: "audio_file", Payload: strings.TrimSpace(strings.TrimPrefix(input, args[0]))}
+		return m, nil
+	case "/handsfree":
+		// 切换免提模式
+		m.eventChan <- Event{Type: "handsfree"}
+		return m, nil
+	case "/continuous":
+		// 切换连续对话
+		m.eventChan <- Event{Type: "continuous"}
+		return m, nil
+	case "/wake":
+		// 切换唤醒模式
+		m.eventChan <- Event{Type: "wake"}
+		return m, nil
+	case "/stop":
+		// 结束连续对话或免提模式
+		m.eventChan <- Event{Type: "voice_stop"}
+		return m, nil
+	case "/confirm":
+		// 切换识别确认模式
+		m.eventChan <- Event{Type: "asr_confirm"}
+		return m, nil
+	}
+
+	m.notification = fmt.Sprintf("未知命令: %s", args[0])
+	return m, m.clearNotification()
+}
+
+// editLexicon 用$EDITOR打开发音词典，退出编辑器后通知重新加载
+func (m model) editLexicon() tea.Cmd {
+	if m.lexiconFile == "" {
+		return func() tea.Msg { return lexiconEditedMsg{errors.New("未配置发音词典文件")} }
+	}
+
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+	return tea.ExecProcess(exec.Command(editor, m.lexiconFile), func(err error) tea.Msg {
+		return lexiconEditedMsg{err}
+	})
+}
@@ -0,0 +1,62 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+)
+
+// 正在朗读的文字的样式
+var speakingStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("0")).Background(lipgloss.Color("11")).Bold(true)
+
+// Speaking 正在朗读第Message条聊天记录中[Start, Stop)的文字（按rune计）
+type Speaking struct {
+	Message int `json:"message"`
+	Start   int `json:"start"`
+	Stop    int `json:"stop"`
+}
+
+// WrapWordsStyled 和WrapWords一样折行，第i个字用styles[classOf(i)]渲染
+// 返回折行后的内容，以及第mark个字所在的行（不存在时为-1）
+func WrapWordsStyled(s string, maxWidth int, styles []lipgloss.Style, classOf func(i int) int, mark int) (string, int) {
+	var lines []string
+	var line, seg strings.Builder
+	class := -1
+	currentWidth := 0
+	markLine := -1
+
+	flush := func() {
+		if seg.Len() == 0 {
+			return
+		}
+		line.WriteString(styles[class].Render(seg.String()))
+		seg.Reset()
+	}
+
+	i := 0
+	for _, r := range s {
+		charWidth := runewidth.RuneWidth(r)
+		if currentWidth+charWidth > maxWidth {
+			flush()
+			lines = append(lines, line.String())
+			line.Reset()
+			currentWidth = 0
+		}
+
+		if c := classOf(i); c != class {
+			flush()
+			class = c
+		}
+		if i == mark {
+			markLine = len(lines)
+		}
+		seg.WriteRune(r)
+		currentWidth += charWidth
+		i++
+	}
+	flush()
+	lines = append(lines, line.String())
+
+	return strings.Join(lines, "\n"), markLine
+}
@@ -0,0 +1,78 @@
+package tui
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Level 录音的音量，RMS和Peak归一化到0~1
+type Level struct {
+	RMS     float64       `json:"rms"`
+	Peak    float64       `json:"peak"`
+	Elapsed time.Duration `json:"elapsed"` // 已经录了多久
+	Silence time.Duration `json:"silence"` // 已经连续多久没有声音
+}
+
+// 录音时连续没有声音超过silenceWarning，提示检查麦克风
+const silenceWarning = 3 * time.Second
+
+// 音量条的格数和能显示的最小音量（dB），说话的音量一般在-40~-10dB
+const (
+	levelBarWidth = 30
+	levelMinDB    = -60
+)
+
+var (
+	levelStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+	levelHot     = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	warningStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+)
+
+// renderLevel 渲染音量条和录音时长，音量按分贝显示，峰值用竖线标出。
+// warnSilence为true时连续没有声音太久会提示
+func renderLevel(l Level, warnSilence bool) string {
+	rms, peak := levelCells(l.RMS), levelCells(l.Peak)
+	var bar strings.Builder
+	for i := 0; i < levelBarWidth; i++ {
+		switch {
+		case i < rms:
+			bar.WriteString("█")
+		case i == peak-1:
+			bar.WriteString("│")
+		default:
+			bar.WriteString("·")
+		}
+	}
+	style := levelStyle
+	if l.Peak >= 0.99 {
+		// 削波了，音量太大
+		style = levelHot
+	}
+
+	seconds := int(l.Elapsed / time.Second)
+	s := fmt.Sprintf("🎤 %s %02d:%02d", style.Render(bar.String()), seconds/60, seconds%60)
+	if warnSilence && l.Silence >= silenceWarning {
+		s += " " + warningStyle.Render(fmt.Sprintf("已经%d秒没有声音，请检查麦克风", int(l.Silence/time.Second)))
+	}
+	return s
+}
+
+// levelCells 音量对应的格数
+func levelCells(v float64) int {
+	if v <= 0 {
+		return 0
+	}
+	db := 20 * math.Log10(v)
+	cells := int(math.Round((db - levelMinDB) / -levelMinDB * levelBarWidth))
+	if cells < 0 {
+		return 0
+	}
+	if cells > levelBarWidth {
+		return levelBarWidth
+	}
+	return cells
+}
@@ -0,0 +1,81 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	log "github.com/sirupsen/logrus"
+)
+
+// 朗读回答时的播放控制快捷键，对应发给主程序的playback事件
+var playbackKeys = map[string]string{
+	"ctrl+p":    "pause", // 暂停/继续
+	"ctrl+n":    "next",  // 跳到下一句
+	"ctrl+x":    "stop",  // 停止朗读这个回答
+	"ctrl+up":   "volume_up",
+	"ctrl+down": "volume_down",
+}
+
+// WithPlaybackControls 播放控制发到ch，主程序忙着处理一轮对话时也能马上执行。
+// 不设置时和其他事件一样发到事件通道
+func WithPlaybackControls(ch chan<- Event) Option {
+	return func(m *model) {
+		m.controlChan = ch
+	}
+}
+
+// sendControl 发送播放控制。来不及处理时丢掉，连续按键时旧的控制已经没有意义，也不能卡住界面
+func (m model) sendControl(e Event) {
+	if m.controlChan == nil {
+		m.eventChan <- e
+		return
+	}
+	select {
+	case m.controlChan <- e:
+	default:
+		log.Debugf("播放控制来不及处理，丢掉: %s", e.Payload)
+	}
+}
+
+// trySend 发送重播、重新合成的事件。主程序正在处理一轮对话时收不了，丢掉并提示，不能卡住界面
+func (m *model) trySend(e Event) tea.Cmd {
+	select {
+	case m.eventChan <- e:
+		return nil
+	default:
+		log.Debugf("主程序正忙，丢掉事件: %s", e.Type)
+		m.notification = "正在朗读回答，请稍后再试"
+		return m.clearNotification()
+	}
+}
+
+// 重播上一个回答的快捷键
+const replayKey = "ctrl+o"
+
+// 聊天历史中选中回答后，按这个键用当前的音色和情感重新合成
+const resynthesizeKey = "r"
+
+const historyHelp = "聊天历史中 ←/→ 选择回答，回车重播，r 重新合成"
+
+const playbackHelp = "ctrl+p 暂停/继续 • ctrl+n 下一句 • ctrl+x 停止朗读 • ctrl+↑/↓ 音量 • ctrl+o 重播"
+
+// nextAnswer 从第from条聊天记录往step方向找下一个回答，from为-1时选中最后一个回答。没有时返回from
+func nextAnswer(history []ChatMessage, from, step int) int {
+	i := from + step
+	if from < 0 {
+		i, step = len(history)-1, -1
+	}
+	for ; i >= 0 && i < len(history); i += step {
+		if history[i].Role != "user" {
+			return i
+		}
+	}
+	return from
+}
+
+// assistantStyle 第i条回答的边框样式，选中的回答加粗高亮
+func (m *model) assistantStyle(i int) lipgloss.Style {
+	if i == m.selected {
+		return selectedAssistantStyle
+	}
+	return assistantStyle
+}
@@ -0,0 +1,69 @@
+package tui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// 默认的按键说话快捷键
+const defaultPTTKey = "ctrl+r"
+
+// 按住说话时，超过pttRelease没有收到按键的自动重复，就认为松开了。
+// 终端的自动重复一般在按下后250~600ms开始，所以这个值不能太小
+const pttRelease = 700 * time.Millisecond
+
+// WithPushToTalk 设置按键说话的快捷键。hold为false时按一下开始、再按一下结束；
+// 为true时按住说话、松开结束。终端不会报告按键松开，所以通过按键的自动重复是否停止来判断
+func WithPushToTalk(key string, hold bool) Option {
+	return func(m *model) {
+		if key != "" {
+			m.pttKey = key
+		}
+		m.pttHold = hold
+	}
+}
+
+type pttCheckMsg struct{}
+
+// pushToTalk 处理按键说话的快捷键
+func (m model) pushToTalk() (tea.Model, tea.Cmd) {
+	if !m.pttHold {
+		return m, toggleRecording
+	}
+
+	m.pttLast = time.Now()
+	if !m.isRecording {
+		m.isRecording = true
+		m.level = Level{}
+		m.startRecording()
+	}
+	// 连续对话时录音是主程序开始的，第一次按下时才开始检查松开
+	if m.pttHolding {
+		return m, nil
+	}
+	m.pttHolding = true
+	return m, checkPTTRelease()
+}
+
+// pttCheck 按住说话时，按键不再重复就结束录音
+func (m model) pttCheck() (tea.Model, tea.Cmd) {
+	if !m.isRecording {
+		m.pttHolding = false
+		return m, nil
+	}
+	if time.Since(m.pttLast) < pttRelease {
+		return m, checkPTTRelease()
+	}
+	m.pttHolding = false
+	m.isRecording = false
+	m.level = Level{}
+	m.stopRecording()
+	return m, nil
+}
+
+func checkPTTRelease() tea.Cmd {
+	return tea.Tick(100*time.Millisecond, func(time.Time) tea.Msg {
+		return pttCheckMsg{}
+	})
+}
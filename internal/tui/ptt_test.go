@@ -0,0 +1,124 @@
+package tui
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	log "github.com/sirupsen/logrus"
+)
+
+var pttPress = tea.KeyMsg{Type: tea.KeyCtrlR}
+
+// pttModel 测试用的界面，返回界面发给主程序的事件通道
+func pttModel(hold bool) (model, chan Event) {
+	out := make(chan Event, 10)
+	m := InitialModel(log.StandardLogger(), out, make(chan Event), WithPushToTalk("ctrl+r", hold))
+	m.notificationCh = make(chan string, 10)
+	return m, out
+}
+
+// update 把msg交给界面，返回新的界面和命令
+func update(m model, msg tea.Msg) (model, tea.Cmd) {
+	next, cmd := m.Update(msg)
+	return next.(model), cmd
+}
+
+// sent 取出界面发给主程序的所有事件类型
+func sent(out chan Event) string {
+	var types []string
+	for {
+		select {
+		case e := <-out:
+			types = append(types, e.Type)
+		default:
+			return fmt.Sprint(types)
+		}
+	}
+}
+
+func TestPushToTalkToggle(t *testing.T) {
+	m, out := pttModel(false)
+
+	// 按一下开始，再按一下结束
+	for _, want := range []string{"[audio_start]", "[audio_stop]"} {
+		var cmd tea.Cmd
+		m, cmd = update(m, pttPress)
+		if cmd == nil {
+			t.Fatal("toggle mode should return a toggle command")
+		}
+		m, _ = update(m, cmd())
+		if got := sent(out); got != want {
+			t.Errorf("events = %s, want %s", got, want)
+		}
+	}
+	if m.isRecording {
+		t.Error("still recording after the second press")
+	}
+}
+
+func TestPushToTalkHold(t *testing.T) {
+	m, out := pttModel(true)
+
+	m, cmd := update(m, pttPress)
+	if got := sent(out); got != "[audio_start]" {
+		t.Fatalf("events after press = %s, want [audio_start]", got)
+	}
+	if cmd == nil {
+		t.Fatal("holding should start checking for release")
+	}
+
+	// 按键的自动重复不会重新开始录音，也不会多开一个检查
+	for i := 0; i < 3; i++ {
+		if m, cmd = update(m, pttPress); cmd != nil {
+			t.Error("key repeat started another release check")
+		}
+	}
+	if got := sent(out); got != "[]" {
+		t.Errorf("events after key repeat = %s, want none", got)
+	}
+
+	// 按键还在重复时继续检查
+	if m, cmd = update(m, pttCheckMsg{}); cmd == nil || !m.isRecording {
+		t.Fatal("recording stopped while the key was still repeating")
+	}
+	if got := sent(out); got != "[]" {
+		t.Errorf("events while holding = %s, want none", got)
+	}
+
+	// 超过pttRelease没有收到重复，认为松开了
+	m.pttLast = time.Now().Add(-pttRelease)
+	m, cmd = update(m, pttCheckMsg{})
+	if got := sent(out); got != "[audio_stop]" {
+		t.Errorf("events after release = %s, want [audio_stop]", got)
+	}
+	if cmd != nil || m.isRecording || m.pttHolding {
+		t.Errorf("after release: cmd = %v, recording = %v, holding = %v", cmd != nil, m.isRecording, m.pttHolding)
+	}
+
+	// 再次按下重新开始
+	m, _ = update(m, pttPress)
+	if got := sent(out); got != "[audio_start]" {
+		t.Errorf("events after pressing again = %s, want [audio_start]", got)
+	}
+}
+
+func TestPushToTalkHoldContinuous(t *testing.T) {
+	// 连续对话时录音由主程序开始，按住时不再发送开始事件，松开时结束
+	m, out := pttModel(true)
+	m.isRecording = true
+
+	m, cmd := update(m, pttPress)
+	if cmd == nil {
+		t.Fatal("first press should start checking for release")
+	}
+	if got := sent(out); got != "[]" {
+		t.Errorf("events after press = %s, want none", got)
+	}
+	m.pttLast = time.Now().Add(-pttRelease)
+	update(m, pttCheckMsg{})
+	if got := sent(out); got != "[audio_stop]" {
+		t.Errorf("events after release = %s, want [audio_stop]", got)
+	}
+}
@@ -3,6 +3,7 @@ package tui
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/sashabaranov/go-openai"
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/tts"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -25,6 +27,10 @@ var (
 	// 定义历史记录样式
 	userStyle      = lipgloss.NewStyle().BorderStyle(lipgloss.NormalBorder()).Foreground(lipgloss.Color("15")).Background(lipgloss.Color("2")) // 绿色
 	assistantStyle = lipgloss.NewStyle().BorderStyle(lipgloss.NormalBorder()).Foreground(lipgloss.Color("0")).Background(lipgloss.Color("6"))  // 红色
+	// 聊天历史中选中的回答，边框加粗高亮
+	selectedAssistantStyle = assistantStyle.BorderStyle(lipgloss.ThickBorder()).BorderForeground(lipgloss.Color("11"))
+	// 高亮朗读文字时，其余文字用的样式，颜色和assistantStyle一致
+	assistantTextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("0")).Background(lipgloss.Color("6"))
 )
 
 // 定义一个消息类型，用于在通道中传递的事件
@@ -38,10 +44,22 @@ type ChatMessage struct {
 	Content string
 }
 
+// 界面中可以获得焦点的区域，按Tab依次切换
+const (
+	focusModel = iota
+	focusTone
+	focusEmotion
+	focusEngine
+	focusHistory
+	focusInput
+	numFocus
+)
+
 type model struct {
 	modelList     list.Model
 	toneList      list.Model
 	emotionList   list.Model
+	engineList    list.Model
 	viewport      viewport.Model
 	questionInput textinput.Model
 	// pastQuestions  []string
@@ -52,16 +70,48 @@ type model struct {
 	notification   string
 	notificationCh chan string
 	isRecording    bool
-	processing     bool // 处理中，不允许再输入
+	listening      string // 免提模式下正在聆听时为on，唤醒模式下等待唤醒时为wake，没在聆听时为空
+	level          Level  // 录音的音量，没在录音时为零值
+	processing     bool   // 处理中，不允许再输入
+	emotion        string
+	languageFilter string // 音色列表按语言筛选，为空不限
+	genderFilter   string // 音色列表按性别筛选，为空不限
+	speaking       Speaking
+	cast           Cast           // 角色扮演设定，Name为空表示没有角色扮演
+	speakingLine   int            // 正在朗读的文字在聊天历史中的行，没有时为-1
+	selected       int            // 聊天历史中选中的回答，用于重播，没有选中时为-1
+	selectedLine   int            // 选中的回答在聊天历史中的第一行
+	rawTranscripts map[int]string // 被纠正过的提问在聊天历史中的位置 -> 识别原文
+
+	eventChan   chan Event
+	inChan      chan Event
+	controlChan chan<- Event // 播放控制单独发送，不排在其他事件后面
+	logger      *log.Logger
+
+	lexiconFile string // 发音词典文件，可以在界面中编辑
+
+	pttKey     string    // 按键说话的快捷键
+	pttHold    bool      // 按住说话，松开结束
+	pttLast    time.Time // 按住说话时最后一次收到按键的时间
+	pttHolding bool      // 按住说话时，正在检查按键是否松开
+}
+
+// Option 定制界面的选项
+type Option func(*model)
 
-	eventChan chan Event
-	inChan    chan Event
-	logger    *log.Logger
+// WithLexiconFile 设置发音词典文件，用 /lexicon 命令编辑
+func WithLexiconFile(path string) Option {
+	return func(m *model) {
+		m.lexiconFile = path
+	}
 }
 
 type toggleMsg struct{}
 
-func InitialModel(l *log.Logger, out chan Event, in chan Event) model {
+// 默认音色，和主程序的默认设置一致
+const defaultVoiceType = 101016
+
+func InitialModel(l *log.Logger, out chan Event, in chan Event, opts ...Option) model {
 	modelItems := []list.Item{
 		item{title: "yi-large", desc: "yi-large 模型"},
 		item{title: "hunyuan", desc: "hunyuan 模型"},
@@ -69,40 +119,35 @@ func InitialModel(l *log.Logger, out chan Event, in chan Event) model {
 		// 添加更多模型选项
 	}
 
-	toneItems := []list.Item{
-		item{title: "101016", desc: "智甜-女童声"},
-		item{title: "101040", desc: "智川-四川女声"},
-		item{title: "1009", desc: "智芸-知性女声"},
-		item{title: "101019", desc: "智彤-粤语女声"},
-		// 添加更多音色选项
-	}
-
-	emotionItems := []list.Item{
-		item{title: "neutral", desc: "中性"},
-		item{title: "angry", desc: "生气"},
-		item{title: "exciting", desc: "兴奋"},
-		item{title: "amaze", desc: "震惊"},
-		// 添加更多情感选项
-	}
-
 	questionInput := textinput.New()
 	questionInput.Placeholder = "在此输入问题..."
 	questionInput.Focus()
 
-	return model{
+	m := model{
 		modelList:      list.New(modelItems, list.NewDefaultDelegate(), 0, 0),
-		toneList:       list.New(toneItems, list.NewDefaultDelegate(), 0, 0),
-		emotionList:    list.New(emotionItems, list.NewDefaultDelegate(), 0, 0),
+		toneList:       list.New(toneItems("", ""), list.NewDefaultDelegate(), 0, 0),
+		emotionList:    list.New(emotionItems(defaultVoiceType), list.NewDefaultDelegate(), 0, 0),
+		engineList:     list.New(engineItems(), list.NewDefaultDelegate(), 0, 0),
 		viewport:       viewport.Model{},
 		questionInput:  questionInput,
-		currentFocus:   4, // 先默认选中输入框
+		currentFocus:   focusInput, // 先默认选中输入框
 		notificationCh: make(chan string, 1),
 		isRecording:    false,
 		processing:     false,
+		emotion:        "neutral",
+		speaking:       Speaking{Message: -1},
+		speakingLine:   -1,
+		selected:       -1,
+		rawTranscripts: map[int]string{},
+		pttKey:         defaultPTTKey,
 		eventChan:      out,
 		inChan:         in,
 		logger:         l,
 	}
+	for _, opt := range opts {
+		opt(&m)
+	}
+	return m
 }
 
 func (m model) Init() tea.Cmd {
@@ -128,53 +173,114 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if msg.String() == m.pttKey {
+			return m.pushToTalk()
+		}
+		if action, ok := playbackKeys[msg.String()]; ok {
+			m.sendControl(Event{Type: "playback", Payload: action})
+			return m, nil
+		}
+		if msg.String() == replayKey {
+			return m, m.trySend(Event{Type: "replay"})
+		}
 		switch msg.String() {
 		case "ctrl+c":
 			close(m.eventChan)
 			return m, tea.Quit
 		case "tab":
-			m.currentFocus = (m.currentFocus + 1) % 5
-			if m.currentFocus == 4 {
+			m.currentFocus = (m.currentFocus + 1) % numFocus
+			if m.currentFocus == focusInput {
 				m.questionInput.Focus()
 			} else {
 				m.questionInput.Blur()
 			}
 		case "shift+tab":
-			m.currentFocus = (m.currentFocus - 1 + 5) % 5
-			if m.currentFocus == 4 {
+			m.currentFocus = (m.currentFocus - 1 + numFocus) % numFocus
+			if m.currentFocus == focusInput {
 				m.questionInput.Focus()
 			} else {
 				m.questionInput.Blur()
 			}
+		case "y", "x":
+			// 在音色列表中按语言(y)、性别(x)筛选
+			if m.currentFocus == focusTone && m.toneList.FilterState() == list.Unfiltered {
+				if msg.String() == "y" {
+					m.languageFilter = nextFilter(m.languageFilter, voiceLanguage)
+				} else {
+					m.genderFilter = nextFilter(m.genderFilter, voiceGender)
+				}
+				m.filterTones()
+				return m, nil
+			}
 		case "up":
-			if m.currentFocus == 3 {
+			if m.currentFocus == focusHistory {
 				m.viewport.LineUp(1)
 			}
 		case "down":
-			if m.currentFocus == 3 {
+			if m.currentFocus == focusHistory {
 				m.viewport.LineDown(1)
 			}
+		case "left", "right":
+			// 在聊天历史中选择上一个/下一个回答
+			if m.currentFocus == focusHistory {
+				step := 1
+				if msg.String() == "left" {
+					step = -1
+				}
+				m.selected = nextAnswer(m.chatHistory, m.selected, step)
+				m.viewport.SetContent(m.renderChatHistory(m.viewport.Width))
+				if m.selected >= 0 {
+					m.scrollToLine(m.selectedLine)
+				}
+				return m, nil
+			}
+		case resynthesizeKey:
+			if m.currentFocus == focusHistory && m.selected >= 0 {
+				return m, m.trySend(Event{Type: "resynthesize", Payload: strconv.Itoa(m.selected)})
+			}
 		case "enter":
 			switch m.currentFocus {
-			case 0:
+			case focusModel:
 				selectedModel := m.modelList.SelectedItem().(item)
 				m.notificationCh <- fmt.Sprintf("选择了模型: %s", selectedModel.Title())
 				m.eventChan <- Event{Type: "model", Payload: selectedModel.Title()}
-			case 1:
-				selectedTone := m.toneList.SelectedItem().(item)
+			case focusTone:
+				selectedTone, ok := m.toneList.SelectedItem().(item)
+				if !ok {
+					break
+				}
 				m.notificationCh <- fmt.Sprintf("选择了音色: %s", selectedTone.Title())
 				m.eventChan <- Event{Type: "tone", Payload: selectedTone.Title()}
-			case 2:
+				voiceType, _ := strconv.ParseInt(selectedTone.Title(), 10, 64)
+				if m.selectTone(voiceType) {
+					m.eventChan <- Event{Type: "emotion", Payload: m.emotion}
+				}
+			case focusEmotion:
 				selectedEmotion := m.emotionList.SelectedItem().(item)
+				m.emotion = selectedEmotion.Title()
 				m.notificationCh <- fmt.Sprintf("选择了情感: %s", selectedEmotion.Title())
 				m.eventChan <- Event{Type: "emotion", Payload: selectedEmotion.Title()}
-			case 3:
+			case focusEngine:
+				selectedEngine := m.engineList.SelectedItem().(item)
+				m.notificationCh <- fmt.Sprintf("识别语言: %s", selectedEngine.Description())
+				m.eventChan <- Event{Type: "engine", Payload: selectedEngine.Title()}
+			case focusHistory:
+				if m.selected >= 0 {
+					cmds = append(cmds, m.trySend(Event{Type: "replay", Payload: strconv.Itoa(m.selected)}))
+					break
+				}
 				log.Debug("选择了历史记录框")
-				m.notificationCh <- "选择了历史记录"
-			case 4:
+				m.notificationCh <- "选择了历史记录，按 ←/→ 选择要重播的回答"
+			case focusInput:
 				question := m.questionInput.Value()
 				log.Debug("问题输入完毕", question)
 				m.questionInput.SetValue("")
+				if strings.TrimSpace(question) == "" {
+					break
+				}
+				if strings.HasPrefix(question, "/") {
+					return m.runCommand(question)
+				}
 				m.notificationCh <- fmt.Sprintf("输入了问题: %s", question)
 				m.eventChan <- Event{Type: "question", Payload: question}
 			}
@@ -213,8 +319,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.height = msg.Height
 		m.width = msg.Width
 
-		listHeight := m.height/4 - 2 // 减去边框的高度
-		listWidth := m.width/5 - 2   // 减去边框的宽度
+		listHeight := (m.height-2)/4 - 3 // 左边四个列表，减去边框和标题的高度
+		listWidth := m.width/5 - 2       // 减去边框的宽度
 
 		m.modelList.SetHeight(listHeight)
 		m.modelList.SetWidth(listWidth)
@@ -225,6 +331,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.emotionList.SetHeight(listHeight)
 		m.emotionList.SetWidth(listWidth)
 
+		m.engineList.SetHeight(listHeight)
+		m.engineList.SetWidth(listWidth)
+
 		m.viewport.Width = m.width*4/5 - 2
 		m.viewport.Height = m.height*3/4 - 2 // 设置聊天历史的高度为窗口高度的一半
 		m.viewport.SetContent(m.renderChatHistory(m.viewport.Width))
@@ -233,27 +342,106 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, tea.Batch(m.listenForNotification(), m.clearNotification(), m.waitForInEvent())
 	case eventMsg:
 		log.Debugf("eventMsg: %v", msg)
-		if msg.Type != "history" {
-			break
-		}
-
-		var history []openai.ChatCompletionMessage
-		err := json.Unmarshal([]byte(msg.Payload), &history)
-		if err != nil {
-			log.Errorf("Failed to unmarshal history: %v", err)
-		} else {
-			m.chatHistory = make([]ChatMessage, len(history))
-			for i, msg := range history {
-				m.chatHistory[i] = ChatMessage{
-					Role:    string(msg.Role),
-					Content: msg.Content,
+		switch msg.Type {
+		case "history":
+			var history []openai.ChatCompletionMessage
+			err := json.Unmarshal([]byte(msg.Payload), &history)
+			if err != nil {
+				log.Errorf("Failed to unmarshal history: %v", err)
+			} else {
+				m.chatHistory = make([]ChatMessage, len(history))
+				for i, msg := range history {
+					m.chatHistory[i] = ChatMessage{
+						Role:    string(msg.Role),
+						Content: msg.Content,
+					}
 				}
 			}
+			m.viewport.SetContent(m.renderChatHistory(m.viewport.Width))
+			return m, tea.Batch(m.listenForNotification(), m.clearNotification(), m.waitForInEvent())
+		case "speaking":
+			// 空内容表示朗读结束
+			m.speaking = Speaking{Message: -1}
+			if msg.Payload != "" {
+				if err := json.Unmarshal([]byte(msg.Payload), &m.speaking); err != nil {
+					log.Errorf("Failed to unmarshal speaking: %v", err)
+				}
+			}
+			m.viewport.SetContent(m.renderChatHistory(m.viewport.Width))
+			m.scrollToSpeaking()
+			return m, m.waitForInEvent()
+		case "persona":
+			m.cast = Cast{}
+			if msg.Payload != "" {
+				if err := json.Unmarshal([]byte(msg.Payload), &m.cast); err != nil {
+					log.Errorf("Failed to unmarshal cast: %v", err)
+				}
+			}
+			m.notification = "取消了角色扮演"
+			if m.cast.Name != "" {
+				m.notification = fmt.Sprintf("切换角色扮演: %s", m.cast.Name)
+			}
+			m.viewport.SetContent(m.renderChatHistory(m.viewport.Width))
+			return m, tea.Batch(m.clearNotification(), m.waitForInEvent())
+		case "transcript":
+			// 实时识别的结果，识别结束后为空
+			m.questionInput.SetValue(msg.Payload)
+			m.questionInput.CursorEnd()
+			return m, m.waitForInEvent()
+		case "transcript_raw":
+			var t Transcript
+			if err := json.Unmarshal([]byte(msg.Payload), &t); err != nil {
+				log.Errorf("Failed to unmarshal transcript: %v", err)
+			} else {
+				m.rawTranscripts[t.Message] = t.Raw
+			}
+			return m, m.waitForInEvent()
+		case "confirm":
+			// 确认模式下的识别结果，修改后按回车发送
+			m.questionInput.SetValue(msg.Payload)
+			m.questionInput.CursorEnd()
+			m.currentFocus = focusInput
+			m.questionInput.Focus()
+			m.notification = "识别结果已填入输入框，修改后按回车发送"
+			return m, tea.Batch(m.clearNotification(), m.waitForInEvent())
+		case "recording":
+			// 连续对话时主程序自动开始了下一次录音
+			m.isRecording = msg.Payload == "on"
+			m.level = Level{}
+			return m, m.waitForInEvent()
+		case "listening":
+			m.listening = msg.Payload
+			if m.listening == "off" {
+				m.listening = ""
+			}
+			m.level = Level{}
+			return m, m.waitForInEvent()
+		case "level":
+			if err := json.Unmarshal([]byte(msg.Payload), &m.level); err != nil {
+				log.Errorf("Failed to unmarshal level: %v", err)
+			}
+			return m, m.waitForInEvent()
+		case "record_error":
+			// 没能开始录音，回到未录音的状态
+			m.isRecording = false
+			m.level = Level{}
+			m.notification = msg.Payload
+			return m, tea.Batch(m.clearNotification(), m.waitForInEvent())
+		case "notify":
+			m.notification = msg.Payload
+			return m, tea.Batch(m.clearNotification(), m.waitForInEvent())
 		}
-		m.viewport.SetContent(m.renderChatHistory(m.viewport.Width))
-		return m, tea.Batch(m.listenForNotification(), m.clearNotification(), m.waitForInEvent())
+	case lexiconEditedMsg:
+		if msg.err != nil {
+			m.notification = fmt.Sprintf("编辑发音词典失败: %v", msg.err)
+			return m, m.clearNotification()
+		}
+		m.eventChan <- Event{Type: "lexicon_reload"}
+	case pttCheckMsg:
+		return m.pttCheck()
 	case toggleMsg:
 		m.isRecording = !m.isRecording
+		m.level = Level{}
 		if m.isRecording {
 			m.startRecording()
 		} else {
@@ -262,13 +450,15 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 
 	switch m.currentFocus {
-	case 0:
+	case focusModel:
 		m.modelList, _ = m.modelList.Update(msg)
-	case 1:
+	case focusTone:
 		m.toneList, _ = m.toneList.Update(msg)
-	case 2:
+	case focusEmotion:
 		m.emotionList, _ = m.emotionList.Update(msg)
-	case 4:
+	case focusEngine:
+		m.engineList, _ = m.engineList.Update(msg)
+	case focusInput:
 		m.questionInput, _ = m.questionInput.Update(msg)
 	}
 
@@ -282,12 +472,13 @@ func (m model) clearNotification() tea.Cmd {
 }
 func (m model) View() string {
 	// log.Debugf("View, height: %d, width: %d, currentFocus:%v\n", m.height, m.width, m.currentFocus)
-	// 左边三个设置项
+	// 左边四个设置项
 	leftColumn := lipgloss.JoinVertical(
 		lipgloss.Left,
-		m.renderList("模型选择", m.modelList, 0),
-		m.renderList("音色选择", m.toneList, 1),
-		m.renderList("情感选择", m.emotionList, 2),
+		m.renderList("模型选择", m.modelList, focusModel),
+		m.renderList(m.toneTitle(), m.toneList, focusTone),
+		m.renderList("情感选择", m.emotionList, focusEmotion),
+		m.renderList("识别语言", m.engineList, focusEngine),
 	)
 	// 右边，下面，是输入框
 	inputWidth := m.viewport.Width
@@ -295,15 +486,22 @@ func (m model) View() string {
 	// log.Debugf("View, height: %d, width: %d, viewport:(%v,%v) input:(%v,%v)\n", m.height, m.width,m.viewport.Width, m.viewport.Height, inputWidth, inputHeight)
 
 	if m.isRecording {
-		m.questionInput.Placeholder = "正在录音中，再次点击结束录音..."
+		m.questionInput.Placeholder = fmt.Sprintf("正在录音中，再次点击或按 %s 结束录音...", m.pttKey)
+		if m.pttHold {
+			m.questionInput.Placeholder = fmt.Sprintf("正在录音中，松开 %s 结束录音...", m.pttKey)
+		}
 		m.questionInput.Focus()
+	} else if m.listening == "wake" {
+		m.questionInput.Placeholder = "待机中，说出唤醒词开始提问..."
+	} else if m.listening != "" {
+		m.questionInput.Placeholder = "免提模式：请直接说话，说完稍等即可..."
 	} else {
 		m.questionInput.Placeholder = "请输入..."
 	}
 
 	m.viewport.SetContent(m.renderChatHistory(m.viewport.Width))
 	viewRender := blurredStyle.Render("聊天历史\n" + m.viewport.View())
-	if m.currentFocus == 3 {
+	if m.currentFocus == focusHistory {
 		viewRender = focusedStyle.Render("聊天历史\n" + m.viewport.View())
 	}
 
@@ -314,7 +512,7 @@ func (m model) View() string {
 			Width(inputWidth).
 			Height(inputHeight).
 			Align(lipgloss.Left).
-			Render(m.questionInput.View()),
+			Render(m.inputView()),
 	)
 
 	ui := lipgloss.JoinHorizontal(lipgloss.Left, leftColumn, rightColumn)
@@ -322,7 +520,16 @@ func (m model) View() string {
 	if m.notification != "" {
 		notification = lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Render(m.notification)
 	}
-	return ui + "\n" + notification + "\n" + helpStyle.Render("按 Tab 切换焦点 • 按 q 退出")
+	return ui + "\n" + notification + "\n" + helpStyle.Render("按 Tab 切换焦点 • 按 "+m.pttKey+" 说话 • "+playbackHelp+" • "+historyHelp+" • 音色列表中按 y/x 按语言/性别筛选 • 识别语言选 auto 时跟随音色 • 按 q 退出")
+}
+
+// inputView 输入框，录音或聆听时下面显示音量条
+func (m model) inputView() string {
+	if (!m.isRecording && m.listening == "") || m.level.Elapsed == 0 {
+		return m.questionInput.View()
+	}
+	// 免提和唤醒模式下没人说话是正常的，只在手动录音时提示
+	return m.questionInput.View() + "\n\n" + renderLevel(m.level, m.isRecording)
 }
 
 func (m model) renderList(title string, l list.Model, index int) string {
@@ -337,15 +544,33 @@ func (m *model) renderChatHistory(width int) string {
 	textWidth := width*4/5 - 4 // 减去边框的宽度
 	// log.Debugf("renderChatHistory, width:%v, textWidth:%v", width, textWidth)
 
-	for _, msg := range m.chatHistory {
+	m.speakingLine = -1
+	for i, msg := range m.chatHistory {
 		var content string
-		wrappedContent := WrapWords(msg.Content, textWidth)
-		if msg.Role == "user" {
-			content = userStyle.
+		if i == m.selected {
+			m.selectedLine = strings.Count(chatContent.String(), "\n")
+		}
+		if msg.Role != "user" && (i == m.speaking.Message || m.cast.Name != "") {
+			wrappedContent, line := m.renderAssistant(i, msg.Content, textWidth)
+			if line >= 0 {
+				// 加上前面的聊天记录和上边框
+				m.speakingLine = strings.Count(chatContent.String(), "\n") + 1 + line
+			}
+			content = m.assistantStyle(i).
 				Align(lipgloss.Left).
+				MarginLeft(width / 5).
 				Render(wrappedContent)
+			chatContent.WriteString(content + "\n")
+			continue
+		}
+
+		if msg.Role == "user" {
+			content = m.renderUser(i, msg.Content, textWidth)
 		} else {
-			content = assistantStyle.
+			// 停顿标记是给语音合成的，不显示
+			display, _ := tts.StripPauses(msg.Content)
+			wrappedContent := WrapWords(display, textWidth)
+			content = m.assistantStyle(i).
 				Align(lipgloss.Left).
 				// Align(lipgloss.Right). // 左右为难，文本的对齐和边框都是这个？
 				// PaddingLeft(width / 5).
@@ -358,6 +583,20 @@ func (m *model) renderChatHistory(width int) string {
 	return chatContent.String()
 }
 
+// scrollToSpeaking 正在朗读的文字不在可见范围时，滚动聊天历史让它出现在上部三分之一处
+func (m *model) scrollToSpeaking() {
+	if m.speakingLine >= 0 {
+		m.scrollToLine(m.speakingLine)
+	}
+}
+
+// scrollToLine 聊天历史的第line行不在可见范围时，滚动让它出现在上部三分之一处
+func (m *model) scrollToLine(line int) {
+	if line < m.viewport.YOffset || line >= m.viewport.YOffset+m.viewport.Height {
+		m.viewport.SetYOffset(line - m.viewport.Height/3)
+	}
+}
+
 func (m model) startRecording() {
 	m.eventChan <- Event{Type: "audio_start", Payload: ""}
 	m.notificationCh <- "开始录音"
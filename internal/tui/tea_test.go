@@ -3,8 +3,13 @@ package tui
 import (
 	"strings"
 	"testing"
+	"time"
 
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/mattn/go-runewidth"
+	"github.com/muesli/termenv"
+	log "github.com/sirupsen/logrus"
 	"golang.org/x/text/width"
 )
 
@@ -64,3 +69,87 @@ func TestWordWrap(t *testing.T) {
 	t.Log(wrapped)
 
 }
+
+func TestNextAnswer(t *testing.T) {
+	history := []ChatMessage{
+		{Role: "user"}, {Role: "assistant"},
+		{Role: "user"}, {Role: "assistant"},
+		{Role: "user"},
+	}
+	tests := []struct {
+		from, step, want int
+	}{
+		{-1, -1, 3}, // 没有选中时从最后一个回答开始
+		{-1, 1, 3},
+		{3, -1, 1},
+		{1, -1, 1}, // 已经是第一个回答
+		{3, 1, 3},  // 后面只有提问
+		{1, 1, 3},
+	}
+	for _, tt := range tests {
+		if got := nextAnswer(history, tt.from, tt.step); got != tt.want {
+			t.Errorf("nextAnswer(%d, %d) = %d, want %d", tt.from, tt.step, got, tt.want)
+		}
+	}
+	if got := nextAnswer(nil, -1, -1); got != -1 {
+		t.Errorf("nextAnswer on empty history = %d, want -1", got)
+	}
+}
+
+func TestPlaybackControls(t *testing.T) {
+	// 主程序来不及处理时丢掉多余的控制，界面不会卡住
+	controls := make(chan Event, 1)
+	m := InitialModel(log.StandardLogger(), make(chan Event), make(chan Event), WithPlaybackControls(controls))
+	for i := 0; i < 3; i++ {
+		next, _ := m.Update(tea.KeyMsg{Type: tea.KeyCtrlP})
+		m = next.(model)
+	}
+	if len(controls) != 1 {
+		t.Fatalf("%d controls queued, want 1", len(controls))
+	}
+	if e := <-controls; e.Type != "playback" || e.Payload != "pause" {
+		t.Errorf("control = %+v, want playback pause", e)
+	}
+}
+
+func TestReplayWhileBusy(t *testing.T) {
+	// 主程序正在朗读回答，没有人读事件通道：连按两次重播不会卡住界面
+	out := make(chan Event, 1)
+	m := InitialModel(log.StandardLogger(), out, make(chan Event))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 2; i++ {
+			next, _ := m.Update(tea.KeyMsg{Type: tea.KeyCtrlO})
+			m = next.(model)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Update blocked on the event channel")
+	}
+	if len(out) != 1 || m.notification == "" {
+		t.Errorf("queued %d events, notification %q; want 1 event and a busy notice", len(out), m.notification)
+	}
+}
+
+func TestPauseMarkersHidden(t *testing.T) {
+	m := InitialModel(log.StandardLogger(), make(chan Event), make(chan Event))
+	m.chatHistory = []ChatMessage{
+		{Role: "user", Content: "开始吧"},
+		{Role: "assistant", Content: "好的[pause:500]，我们开始吧。"},
+	}
+	if got := m.renderChatHistory(80); strings.Contains(got, "pause") || !strings.Contains(got, "好的，我们开始吧。") {
+		t.Errorf("answer rendered as:\n%s", got)
+	}
+
+	// 正在朗读原文中的“我们”，高亮的还是这两个字
+	defer lipgloss.SetColorProfile(lipgloss.ColorProfile())
+	lipgloss.SetColorProfile(termenv.ANSI)
+	m.speaking = Speaking{Message: 1, Start: 14, Stop: 16}
+	got := m.renderChatHistory(80)
+	if strings.Contains(got, "pause") || !strings.Contains(got, speakingStyle.Render("我们")) {
+		t.Errorf("answer rendered as:\n%s", got)
+	}
+}
@@ -0,0 +1,28 @@
+package tui
+
+import (
+	"github.com/charmbracelet/lipgloss"
+)
+
+// 纠正前的识别原文，颜色比提问淡一些
+var rawTranscriptStyle = lipgloss.NewStyle().BorderStyle(lipgloss.NormalBorder()).Foreground(lipgloss.Color("7")).Faint(true)
+
+// Transcript 纠正前的识别原文，Message为对应的提问在聊天历史中的位置
+type Transcript struct {
+	Message int    `json:"message"`
+	Raw     string `json:"raw"`
+}
+
+// renderUser 渲染一条提问，识别结果被纠正过时左边是识别原文，右边是纠正后的提问
+func (m *model) renderUser(index int, content string, textWidth int) string {
+	raw, ok := m.rawTranscripts[index]
+	if !ok {
+		return userStyle.Align(lipgloss.Left).Render(WrapWords(content, textWidth))
+	}
+
+	half := textWidth / 2
+	return lipgloss.JoinHorizontal(lipgloss.Top,
+		rawTranscriptStyle.Render(WrapWords("识别："+raw, half)),
+		userStyle.Align(lipgloss.Left).Render(WrapWords("纠正："+content, half)),
+	)
+}
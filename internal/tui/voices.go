@@ -0,0 +1,110 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/charmbracelet/bubbles/list"
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/asr"
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/tts"
+)
+
+// toneItems 按语言和性别筛选音色，条件为空表示不限。不支持合成采样率的音色不列出
+func toneItems(language, gender string) []list.Item {
+	var items []list.Item
+	for _, v := range tts.VoicesFor(tts.SampleRate) {
+		if (language != "" && v.Language != language) || (gender != "" && v.Gender != gender) {
+			continue
+		}
+		items = append(items, item{
+			title: strconv.FormatInt(v.ID, 10),
+			desc:  fmt.Sprintf("%s-%s·%s", v.Name, v.Desc, v.Language),
+		})
+	}
+	return items
+}
+
+// emotionItems 返回音色支持的情感，目录中没有的音色只提供中性
+func emotionItems(voiceType int64) []list.Item {
+	emotions := []string{"neutral"}
+	if v, ok := tts.LookupVoice(voiceType); ok && len(v.Emotions) > 0 {
+		emotions = v.Emotions
+	}
+
+	items := make([]list.Item, 0, len(emotions))
+	for _, e := range emotions {
+		items = append(items, item{title: e, desc: tts.EmotionNames[e]})
+	}
+	return items
+}
+
+// engineItems 识别引擎列表，第一项auto表示跟随所选音色的语言
+func engineItems() []list.Item {
+	items := []list.Item{item{title: "auto", desc: "跟随音色语言"}}
+	for _, e := range asr.Engines {
+		items = append(items, item{title: e.ID, desc: e.Name})
+	}
+	return items
+}
+
+// nextFilter 在所有音色的某个属性（语言、性别）中循环切换筛选条件，""表示不限
+func nextFilter(current string, attr func(tts.VoiceInfo) string) string {
+	var values []string
+	seen := map[string]bool{}
+	for _, v := range tts.VoicesFor(tts.SampleRate) {
+		if a := attr(v); !seen[a] {
+			seen[a] = true
+			values = append(values, a)
+		}
+	}
+
+	if current == "" {
+		return values[0]
+	}
+	for i, v := range values {
+		if v == current && i+1 < len(values) {
+			return values[i+1]
+		}
+	}
+	return ""
+}
+
+func voiceLanguage(v tts.VoiceInfo) string { return v.Language }
+func voiceGender(v tts.VoiceInfo) string   { return v.Gender }
+
+// toneTitle 音色列表的标题，带上当前的筛选条件
+func (m model) toneTitle() string {
+	language, gender := m.languageFilter, m.genderFilter
+	if language == "" {
+		language = "全部语言"
+	}
+	if gender == "" {
+		gender = "全部性别"
+	}
+	return fmt.Sprintf("音色选择 [%s/%s]", language, gender)
+}
+
+// filterTones 按筛选条件刷新音色列表
+func (m *model) filterTones() {
+	m.toneList.ResetSelected()
+	m.toneList.SetItems(toneItems(m.languageFilter, m.genderFilter))
+}
+
+// selectTone 选中音色后，情感列表只保留该音色支持的情感。
+// 当前情感不被支持时改为第一个支持的情感，返回是否发生了改变
+func (m *model) selectTone(voiceType int64) bool {
+	m.emotionList.ResetSelected()
+	m.emotionList.SetItems(emotionItems(voiceType))
+
+	if v, ok := tts.LookupVoice(voiceType); !ok || v.SupportsEmotion(m.emotion) {
+		for i, it := range m.emotionList.Items() {
+			if it.(item).title == m.emotion {
+				m.emotionList.Select(i)
+			}
+		}
+		return false
+	}
+
+	m.emotion = m.emotionList.Items()[0].(item).title
+	return true
+}
@@ -0,0 +1,101 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+	log "github.com/sirupsen/logrus"
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/tts"
+)
+
+func titles(items []list.Item) []string {
+	var s []string
+	for _, it := range items {
+		s = append(s, it.(item).title)
+	}
+	return s
+}
+
+func TestToneItems(t *testing.T) {
+	if n := len(toneItems("", "")); n != len(tts.VoicesFor(tts.SampleRate)) {
+		t.Errorf("unfiltered: %d items, want %d", n, len(tts.VoicesFor(tts.SampleRate)))
+	}
+
+	english := toneItems("英文", "")
+	if len(english) == 0 {
+		t.Fatal("no English voices")
+	}
+	for _, it := range english {
+		if !strings.HasSuffix(it.(item).desc, "·英文") {
+			t.Errorf("English filter returned %+v", it)
+		}
+	}
+
+	if got := titles(toneItems("英文", "女")); fmt.Sprint(got) != "[1051 101051]" {
+		t.Errorf("English female voices = %v, want [1051 101051]", got)
+	}
+	if got := toneItems("火星文", ""); len(got) != 0 {
+		t.Errorf("unknown language returned %v", titles(got))
+	}
+}
+
+func TestEmotionItems(t *testing.T) {
+	if got := titles(emotionItems(101016)); fmt.Sprint(got) != "[neutral happy sajiao angry exciting amaze]" {
+		t.Errorf("101016 emotions = %v", got)
+	}
+	// 目录中没有的音色只提供中性
+	if got := titles(emotionItems(1)); fmt.Sprint(got) != "[neutral]" {
+		t.Errorf("unknown voice emotions = %v", got)
+	}
+}
+
+func TestNextFilter(t *testing.T) {
+	// 从“不限”开始，依次经过每种语言各一次，最后回到“不限”
+	seen := map[string]bool{}
+	filter := nextFilter("", voiceLanguage)
+	for filter != "" {
+		if seen[filter] {
+			t.Fatalf("language %q visited twice", filter)
+		}
+		seen[filter] = true
+		filter = nextFilter(filter, voiceLanguage)
+	}
+	for _, v := range tts.Voices() {
+		if !seen[v.Language] {
+			t.Errorf("language %q never selected", v.Language)
+		}
+	}
+
+	if got := nextFilter("", voiceGender); got != tts.Voices()[0].Gender {
+		t.Errorf("first gender = %q", got)
+	}
+	if got := nextFilter("不存在", voiceGender); got != "" {
+		t.Errorf("unknown filter moved to %q, want back to all", got)
+	}
+}
+
+func TestSelectTone(t *testing.T) {
+	m := InitialModel(log.StandardLogger(), nil, nil)
+
+	// 支持当前情感时保持不变，并选中它
+	m.emotion = "sajiao"
+	if m.selectTone(101016) {
+		t.Error("101016 supports sajiao, emotion should not change")
+	}
+	if got := m.emotionList.SelectedItem().(item).title; got != "sajiao" {
+		t.Errorf("selected emotion = %q, want sajiao", got)
+	}
+
+	// 不支持时改为第一个支持的情感
+	if !m.selectTone(1050) {
+		t.Error("1050 does not support sajiao, emotion should change")
+	}
+	if m.emotion != "neutral" {
+		t.Errorf("emotion = %q, want neutral", m.emotion)
+	}
+	if got := titles(m.emotionList.Items()); fmt.Sprint(got) != "[neutral]" {
+		t.Errorf("emotion list = %v, want [neutral]", got)
+	}
+}
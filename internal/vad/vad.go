@@ -0,0 +1,211 @@
+package vad
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/audio"
+)
+
+// Config 语音活动检测的参数，零值的字段使用DefaultConfig中的值
+type Config struct {
+	Frame      time.Duration // 每次判断的帧长
+	PreRoll    time.Duration // 检测到说话前保留的录音，避免第一个字被截掉
+	EndSilence time.Duration // 说话后静音这么久认为一句话结束
+	MinSpeech  time.Duration // 有声音的时间短于这个值时认为是杂音，丢弃
+	MaxSpeech  time.Duration // 一句话最长的时间，超过后直接结束
+
+	// Threshold 有声音的最小均方根振幅（16位采样），为0时根据背景噪声自动调整
+	Threshold float64
+}
+
+var DefaultConfig = Config{
+	Frame:      20 * time.Millisecond,
+	PreRoll:    300 * time.Millisecond,
+	EndSilence: 800 * time.Millisecond,
+	MinSpeech:  250 * time.Millisecond,
+	MaxSpeech:  55 * time.Second,
+}
+
+// 自动阈值时，说话的音量至少是背景噪声的noiseRatio倍，并且不低于minThreshold
+const (
+	noiseRatio   = 3
+	minThreshold = 300
+)
+
+// 过零率高于maxZCR的帧多半是“嘶嘶”的高频噪声，不当作说话
+const maxZCR = 0.5
+
+func (c Config) withDefaults() Config {
+	d := DefaultConfig
+	if c.Frame > 0 {
+		d.Frame = c.Frame
+	}
+	if c.PreRoll > 0 {
+		d.PreRoll = c.PreRoll
+	}
+	if c.EndSilence > 0 {
+		d.EndSilence = c.EndSilence
+	}
+	if c.MinSpeech > 0 {
+		d.MinSpeech = c.MinSpeech
+	}
+	if c.MaxSpeech > 0 {
+		d.MaxSpeech = c.MaxSpeech
+	}
+	d.Threshold = c.Threshold
+	return d
+}
+
+// Detector 基于能量和过零率逐帧判断是否在说话，并跟踪背景噪声的音量
+type Detector struct {
+	cfg   Config
+	noise float64 // 背景噪声的均方根振幅
+}
+
+func NewDetector(cfg Config) *Detector {
+	return &Detector{cfg: cfg.withDefaults()}
+}
+
+// IsSpeech 判断一帧16位单声道PCM是否在说话
+func (d *Detector) IsSpeech(frame []byte) bool {
+	rms, zcr := analyze(frame)
+
+	threshold := d.cfg.Threshold
+	if threshold == 0 {
+		threshold = math.Max(minThreshold, d.noise*noiseRatio)
+	}
+	speech := rms > threshold && zcr < maxZCR
+	if !speech {
+		// 只用非说话的帧更新背景噪声，开始时噪声为0，先快后慢
+		if d.noise == 0 {
+			d.noise = rms
+		} else {
+			d.noise = 0.95*d.noise + 0.05*rms
+		}
+	}
+	return speech
+}
+
+// analyze 计算一帧的均方根振幅和过零率
+func analyze(frame []byte) (rms, zcr float64) {
+	n := len(frame) / 2
+	if n == 0 {
+		return 0, 0
+	}
+	var sum float64
+	crossings := 0
+	prev := int16(0)
+	for i := 0; i < n; i++ {
+		v := int16(binary.LittleEndian.Uint16(frame[i*2:]))
+		sum += float64(v) * float64(v)
+		if i > 0 && (v >= 0) != (prev >= 0) {
+			crossings++
+		}
+		prev = v
+	}
+	return math.Sqrt(sum / float64(n)), float64(crossings) / float64(n)
+}
+
+// Segment 从16k单声道PCM数据流中切出一句句话（包括前面的PreRoll和后面的静音），pcm关闭后关闭返回的通道
+func Segment(pcm <-chan []byte, cfg Config) <-chan []byte {
+	cfg = cfg.withDefaults()
+	out := make(chan []byte, 4)
+	go func() {
+		defer close(out)
+		s := newSegmenter(cfg)
+		for data := range pcm {
+			for _, utterance := range s.feed(data) {
+				out <- utterance
+			}
+		}
+		if utterance := s.flush(); utterance != nil {
+			out <- utterance
+		}
+	}()
+	return out
+}
+
+// segmenter 按帧检测说话的开始和结束
+type segmenter struct {
+	cfg       Config
+	detector  *Detector
+	frameSize int
+	rest      []byte // 不足一帧的数据
+
+	preRoll   [][]byte // 没在说话时最近的几帧
+	utterance []byte   // 正在说的话，为nil表示没在说话
+	speech    time.Duration
+	silence   time.Duration
+}
+
+func newSegmenter(cfg Config) *segmenter {
+	frameSize := int(cfg.Frame.Seconds()*float64(audio.Mono16k.BytesPerSecond())) / 2 * 2
+	return &segmenter{cfg: cfg, detector: &Detector{cfg: cfg}, frameSize: frameSize}
+}
+
+// feed 处理一块数据，返回其中结束的话
+func (s *segmenter) feed(data []byte) [][]byte {
+	var done [][]byte
+	data = append(s.rest, data...)
+	for len(data) >= s.frameSize {
+		frame := data[:s.frameSize:s.frameSize]
+		data = data[s.frameSize:]
+		if utterance := s.frame(frame); utterance != nil {
+			done = append(done, utterance)
+		}
+	}
+	s.rest = append([]byte(nil), data...)
+	return done
+}
+
+func (s *segmenter) frame(frame []byte) []byte {
+	speech := s.detector.IsSpeech(frame)
+	if s.utterance == nil {
+		if !speech {
+			s.preRoll = append(s.preRoll, frame)
+			if max := int(s.cfg.PreRoll / s.cfg.Frame); len(s.preRoll) > max {
+				s.preRoll = s.preRoll[len(s.preRoll)-max:]
+			}
+			return nil
+		}
+		// 开始说话，带上之前的几帧
+		s.utterance = []byte{}
+		for _, f := range s.preRoll {
+			s.utterance = append(s.utterance, f...)
+		}
+		s.preRoll = nil
+		s.speech, s.silence = 0, 0
+	}
+
+	s.utterance = append(s.utterance, frame...)
+	if speech {
+		s.speech += s.cfg.Frame
+		s.silence = 0
+	} else {
+		s.silence += s.cfg.Frame
+	}
+	if s.silence >= s.cfg.EndSilence || audio.Mono16k.Duration(len(s.utterance)) >= s.cfg.MaxSpeech {
+		return s.end()
+	}
+	return nil
+}
+
+// end 一句话结束，有声音的时间太短时丢弃
+func (s *segmenter) end() []byte {
+	utterance, speech := s.utterance, s.speech
+	s.utterance = nil
+	if speech < s.cfg.MinSpeech {
+		return nil
+	}
+	return utterance
+}
+
+// flush 录音结束时，正在说的话也算结束
+func (s *segmenter) flush() []byte {
+	if s.utterance == nil {
+		return nil
+	}
+	return s.end()
+}
@@ -0,0 +1,108 @@
+package vad
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"gitlab.mrlin.cc/kevinlin/ai-tell-you/internal/audio"
+)
+
+func samples(d time.Duration) int { return int(d.Seconds() * 16000) }
+
+// noise 低音量的背景噪声
+func noise(d time.Duration, rng *rand.Rand) []byte {
+	var pcm []byte
+	for i := 0; i < samples(d); i++ {
+		pcm = binary.LittleEndian.AppendUint16(pcm, uint16(int16(rng.Intn(200)-100)))
+	}
+	return pcm
+}
+
+// voice 模拟说话：200Hz的基音加上音量起伏
+func voice(d time.Duration) []byte {
+	var pcm []byte
+	for i := 0; i < samples(d); i++ {
+		t := float64(i) / 16000
+		v := 6000 * (0.6 + 0.4*math.Sin(2*math.Pi*3*t)) * math.Sin(2*math.Pi*200*t)
+		pcm = binary.LittleEndian.AppendUint16(pcm, uint16(int16(v)))
+	}
+	return pcm
+}
+
+// hiss 高频的嘶嘶声，音量大但过零率高
+func hiss(d time.Duration) []byte {
+	var pcm []byte
+	for i := 0; i < samples(d); i++ {
+		v := int16(3000)
+		if i%2 == 1 {
+			v = -v
+		}
+		pcm = binary.LittleEndian.AppendUint16(pcm, uint16(v))
+	}
+	return pcm
+}
+
+func TestSegment(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var recording []byte
+	for _, part := range [][]byte{
+		noise(time.Second, rng),
+		voice(time.Second), // 第一句
+		noise(time.Second, rng),
+		voice(100 * time.Millisecond), // 太短，是杂音
+		noise(time.Second, rng),
+		hiss(500 * time.Millisecond), // 不是说话
+		noise(time.Second, rng),
+		voice(800 * time.Millisecond), // 第二句，后面的静音不够长，录音结束时才结束
+		noise(300*time.Millisecond, rng),
+	} {
+		recording = append(recording, part...)
+	}
+
+	// 分成大小不一的块送进去
+	pcm := make(chan []byte)
+	go func() {
+		for data := recording; len(data) > 0; {
+			n := 1 + rng.Intn(3000)
+			if n > len(data) {
+				n = len(data)
+			}
+			pcm <- data[:n]
+			data = data[n:]
+		}
+		close(pcm)
+	}()
+
+	var got []time.Duration
+	for utterance := range Segment(pcm, Config{}) {
+		got = append(got, audio.Mono16k.Duration(len(utterance)))
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d utterances %v, want 2", len(got), got)
+	}
+	// 第一句：300ms的PreRoll + 1s说话 + 800ms静音
+	if got[0] < 2*time.Second || got[0] > 2200*time.Millisecond {
+		t.Errorf("first utterance is %v, want about 2.1s", got[0])
+	}
+	// 第二句：300ms的PreRoll + 800ms说话 + 300ms静音
+	if got[1] < 1300*time.Millisecond || got[1] > 1500*time.Millisecond {
+		t.Errorf("second utterance is %v, want about 1.4s", got[1])
+	}
+}
+
+func TestMaxSpeech(t *testing.T) {
+	pcm := make(chan []byte, 1)
+	pcm <- voice(3 * time.Second)
+	close(pcm)
+
+	var n int
+	for range Segment(pcm, Config{MaxSpeech: time.Second}) {
+		n++
+	}
+	if n != 3 {
+		t.Errorf("got %d utterances, want 3", n)
+	}
+}